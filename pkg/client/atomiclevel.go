@@ -0,0 +1,142 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// AtomicLevel is a level that can be changed at runtime and shared between loggers. It implements [slog.Leveler], so
+// it can be used as the Level of [slog.HandlerOptions] for the slog adapter, and it can be passed to the logr adapter
+// using its WithLeveler method. It is safe to use concurrently.
+//
+// The level is on the [slog.Level] scale. For logr, V(n) corresponds to slog.Level(-n), so the verbosity is simply the
+// negated level. For example, setting the verbosity to 4 enables V(4) logging and below.
+//
+// AtomicLevel also implements [http.Handler], allowing the level to be read and changed over HTTP. See
+// [AtomicLevel.ServeHTTP] for details.
+type AtomicLevel struct {
+	level slog.LevelVar
+}
+
+// Assert that AtomicLevel implements the [slog.Leveler] and [http.Handler] interfaces.
+var (
+	_ slog.Leveler = (*AtomicLevel)(nil)
+	_ http.Handler = (*AtomicLevel)(nil)
+)
+
+// NewAtomicLevel creates a new AtomicLevel set to the given level.
+func NewAtomicLevel(level slog.Level) *AtomicLevel {
+	atomicLevel := &AtomicLevel{}
+	atomicLevel.level.Set(level)
+
+	return atomicLevel
+}
+
+// Level returns the current level. It implements the [slog.Leveler] interface.
+func (atomicLevel *AtomicLevel) Level() slog.Level {
+	return atomicLevel.level.Level()
+}
+
+// SetLevel changes the level.
+func (atomicLevel *AtomicLevel) SetLevel(level slog.Level) {
+	atomicLevel.level.Set(level)
+}
+
+// Verbosity returns the current level as a logr verbosity, i.e. the negated level.
+func (atomicLevel *AtomicLevel) Verbosity() int {
+	return -int(atomicLevel.level.Level())
+}
+
+// SetVerbosity changes the level to the given logr verbosity, i.e. to slog.Level(-verbosity).
+func (atomicLevel *AtomicLevel) SetVerbosity(verbosity int) {
+	atomicLevel.level.Set(slog.Level(-verbosity))
+}
+
+// String returns the string representation of the current level, e.g. "INFO" or "DEBUG+2".
+func (atomicLevel *AtomicLevel) String() string {
+	return atomicLevel.level.Level().String()
+}
+
+// atomicLevelPayload is the JSON representation of an AtomicLevel used by ServeHTTP. In requests, at most one of the
+// fields may be set.
+type atomicLevelPayload struct {
+	Level     *string `json:"level,omitempty"`
+	Verbosity *int    `json:"verbosity,omitempty"`
+}
+
+// atomicLevelError is the JSON representation of an error returned by ServeHTTP.
+type atomicLevelError struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP implements the [http.Handler] interface. It supports two methods:
+//
+//   - GET returns the current level as JSON, e.g. {"level":"DEBUG","verbosity":4}.
+//   - PUT changes the level. The body is a JSON object with either the level, parsed using [ParseLevel], or the logr
+//     verbosity, e.g. {"level":"debug"} or {"verbosity":4}. It responds with the new level like GET.
+//
+// Errors are returned as JSON objects with an error field. Other methods are rejected with a 405 status code.
+func (atomicLevel *AtomicLevel) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		err := atomicLevel.update(request)
+		if err != nil {
+			writeJSON(writer, http.StatusBadRequest, atomicLevelError{Error: err.Error()})
+
+			return
+		}
+	default:
+		writer.Header().Set("Allow", http.MethodGet+", "+http.MethodPut)
+		writeJSON(writer, http.StatusMethodNotAllowed, atomicLevelError{
+			Error: "only GET and PUT are supported",
+		})
+
+		return
+	}
+
+	level := atomicLevel.Level()
+	verbosity := -int(level)
+	levelName := level.String()
+
+	writeJSON(writer, http.StatusOK, atomicLevelPayload{Level: &levelName, Verbosity: &verbosity})
+}
+
+// update changes the level according to the body of the request.
+func (atomicLevel *AtomicLevel) update(request *http.Request) error {
+	var payload atomicLevelPayload
+
+	err := json.NewDecoder(request.Body).Decode(&payload)
+	if err != nil {
+		return fmt.Errorf("failed to decode request body: %w", err)
+	}
+
+	switch {
+	case payload.Level != nil && payload.Verbosity != nil:
+		return errors.New("must not specify both level and verbosity")
+	case payload.Level != nil:
+		level, err := ParseLevel(*payload.Level)
+		if err != nil {
+			return err
+		}
+
+		atomicLevel.SetLevel(level)
+	case payload.Verbosity != nil:
+		atomicLevel.SetVerbosity(*payload.Verbosity)
+	default:
+		return errors.New("must specify either level or verbosity")
+	}
+
+	return nil
+}
+
+// writeJSON writes the value as the JSON body of the response with the given status code.
+func writeJSON(writer http.ResponseWriter, statusCode int, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+
+	_ = json.NewEncoder(writer).Encode(value)
+}
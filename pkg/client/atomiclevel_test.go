@@ -0,0 +1,111 @@
+package client
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtomicLevel(t *testing.T) {
+	t.Parallel()
+
+	atomicLevel := NewAtomicLevel(slog.LevelInfo)
+	require.Equal(t, slog.LevelInfo, atomicLevel.Level())
+	require.Equal(t, 0, atomicLevel.Verbosity())
+
+	atomicLevel.SetVerbosity(4)
+	require.Equal(t, slog.LevelDebug, atomicLevel.Level())
+	require.Equal(t, "DEBUG", atomicLevel.String())
+
+	atomicLevel.SetLevel(slog.LevelError)
+	require.Equal(t, -8, atomicLevel.Verbosity())
+}
+
+//nolint:funlen // Most of the function is test cases, no need to worry about length.
+func TestAtomicLevel_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+		expectedBody   string
+		expectedLevel  slog.Level
+	}{
+		{
+			name:           "get",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"level":"INFO","verbosity":0}`,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "put-level",
+			method:         http.MethodPut,
+			body:           `{"level":"warn"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"level":"WARN","verbosity":-4}`,
+			expectedLevel:  slog.LevelWarn,
+		},
+		{
+			name:           "put-verbosity",
+			method:         http.MethodPut,
+			body:           `{"verbosity":4}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"level":"DEBUG","verbosity":4}`,
+			expectedLevel:  slog.LevelDebug,
+		},
+		{
+			name:           "put-both",
+			method:         http.MethodPut,
+			body:           `{"level":"warn","verbosity":4}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"must not specify both level and verbosity"}`,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "put-unknown-level",
+			method:         http.MethodPut,
+			body:           `{"level":"verbose"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"unknown level: \"verbose\""}`,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "put-empty",
+			method:         http.MethodPut,
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"must specify either level or verbosity"}`,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "post",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   `{"error":"only GET and PUT are supported"}`,
+			expectedLevel:  slog.LevelInfo,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			atomicLevel := NewAtomicLevel(slog.LevelInfo)
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(testCase.method, "/level", strings.NewReader(testCase.body))
+
+			atomicLevel.ServeHTTP(recorder, request)
+
+			require.Equal(t, testCase.expectedStatus, recorder.Code)
+			require.JSONEq(t, testCase.expectedBody, recorder.Body.String())
+			require.Equal(t, testCase.expectedLevel, atomicLevel.Level())
+		})
+	}
+}
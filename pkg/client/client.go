@@ -2,12 +2,12 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
 // PushPath is the path to the Loki push endpoint. It is not appended to the URL automatically, but left as a constant
@@ -30,17 +30,28 @@ type Client interface {
 	Push(ctx context.Context, entry Entry) error
 }
 
+// Flusher is an optional interface implemented by clients that push entries asynchronously or hold them back, such as
+// the clients in the retry and dedup packages. Flush blocks until all entries pushed so far have been sent or the
+// context is done. Adapters call it when their logger is synced.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
 // LokiClient is a client for pushing log entries to a Loki instance. It implements the [Client] interface.
 type LokiClient struct {
-	url    string
-	client *http.Client
+	url         string
+	client      *http.Client
+	compression Compression
+	timeout     time.Duration
 }
 
-// NewLokiClient creates a new LokiClient with the given URL.
+// NewLokiClient creates a new LokiClient with the given URL. It uses an HTTP client with the transport returned by
+// [NewTransport] and applies [DefaultPushTimeout] to pushes whose context has no deadline.
 func NewLokiClient(url string) *LokiClient {
 	return &LokiClient{
-		url:    url,
-		client: &http.Client{},
+		url:     url,
+		client:  &http.Client{Transport: NewTransport()},
+		timeout: DefaultPushTimeout,
 	}
 }
 
@@ -48,29 +59,81 @@ func NewLokiClient(url string) *LokiClient {
 // goroutines as it returns a new LokiClient struct.
 func (client *LokiClient) WithHTTPClient(httpClient *http.Client) *LokiClient {
 	return &LokiClient{
-		url:    client.url,
-		client: httpClient,
+		url:         client.url,
+		client:      httpClient,
+		compression: client.compression,
+		timeout:     client.timeout,
+	}
+}
+
+// WithCompression sets the Content-Encoding of the requests, see [Compression]. It defaults to [CompressionNone],
+// which sends Loki's native Snappy-compressed protobuf. It is safe to call concurrently from multiple goroutines as it
+// returns a new LokiClient struct.
+func (client *LokiClient) WithCompression(compression Compression) *LokiClient {
+	return &LokiClient{
+		url:         client.url,
+		client:      client.client,
+		compression: compression,
+		timeout:     client.timeout,
+	}
+}
+
+// WithTimeout sets the timeout of pushes whose context has no deadline. It defaults to [DefaultPushTimeout], and a
+// timeout of zero disables it. A deadline of the context always takes precedence, so it can be used to set a longer or
+// shorter timeout for a single push. It is safe to call concurrently from multiple goroutines as it returns a new
+// LokiClient struct.
+func (client *LokiClient) WithTimeout(timeout time.Duration) *LokiClient {
+	return &LokiClient{
+		url:         client.url,
+		client:      client.client,
+		compression: client.compression,
+		timeout:     timeout,
 	}
 }
 
 // Assert that LokiClient implements the Client interface.
 var _ Client = (*LokiClient)(nil)
 
-// Push implements the [Client] interface. It sends the given Entry to Loki.
+// Push implements the [Client] interface. It sends the given Entry to Loki, adding any labels and structured metadata
+// carried by the context, see [MergeContext]. If the context has no deadline, the timeout set using
+// [LokiClient.WithTimeout] is applied.
 func (client *LokiClient) Push(ctx context.Context, entry Entry) error {
-	buf, err := entry.Encode()
+	entry = MergeContext(ctx, entry)
+
+	if _, ok := ctx.Deadline(); !ok && client.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, client.timeout)
+		defer cancel()
+	}
+
+	// The encoder is released after the request is done, although the request body may still hold a reference to it.
+	enc := newEncoder()
+	defer enc.release()
+
+	buf, err := enc.encode(&entry, client.compression)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", client.url, bytes.NewReader(buf))
+	req, err := http.NewRequestWithContext(ctx, "POST", client.url, nil)
 	if err != nil {
 		return err
 	}
 
+	// Setting the body directly avoids copying the pooled buffer. GetBody allows the transport to retry the request,
+	// which is only possible before Do returns and thus while the encoder is still referenced.
+	req.Body = enc.body()
+	req.GetBody = func() (io.ReadCloser, error) { return enc.body(), nil }
+	req.ContentLength = int64(len(buf))
+
 	req.Header.Set("Content-Type", contentTypeProtobuf)
 	req.Header.Set("User-Agent", userAgent)
 
+	if contentEncoding := client.compression.ContentEncoding(); contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+
 	resp, err := client.client.Do(req)
 	if err != nil {
 		return err
@@ -80,24 +143,22 @@ func (client *LokiClient) Push(ctx context.Context, entry Entry) error {
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return errors.Join(&PushStatusError{
-				StatusCode: resp.StatusCode,
-				Status:     resp.Status,
-			}, fmt.Errorf("failed to read response body: %w", err))
+			return errors.Join(NewPushStatusError(resp.StatusCode, resp.Status, nil),
+				fmt.Errorf("failed to read response body: %w", err))
 		}
 
-		return &PushStatusError{
-			StatusCode: resp.StatusCode,
-			Status:     resp.Status,
-			Body:       body,
-		}
+		return NewPushStatusError(resp.StatusCode, resp.Status, body)
 	}
 
 	return nil
 }
 
 // PushStatusError is an error that represents a failed push request to Loki. It contains the status code, status
-// message, and body of the response. It implements the [error] interface.
+// message, and body of the response, as well as the reason and the affected stream parsed from the body. It implements
+// the [error] interface.
+//
+// Use [errors.As] to inspect the reason instead of matching the body, e.g. to decide whether to retry the push with
+// [PushStatusError.Retryable].
 type PushStatusError struct {
 	// StatusCode is the status code of the response.
 	StatusCode int
@@ -105,6 +166,10 @@ type PushStatusError struct {
 	Status string
 	// Body is the body of the response.
 	Body []byte
+	// Reason is the category of the error, or [ReasonUnknown] if the body did not match any known error of Loki.
+	Reason PushErrorReason
+	// Stream are the labels of the stream the error refers to, or empty if the body does not mention one.
+	Stream LabelString
 }
 
 var _ error = (*PushStatusError)(nil)
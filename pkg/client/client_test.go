@@ -7,7 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
-	"github.com/tslnc04/loki-logger/pkg/internal/fake"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
 )
 
 var (
@@ -15,6 +15,7 @@ var (
 		StatusCode: 500,
 		Status:     "500 Internal Server Error",
 		Body:       []byte("Internal Server Error"),
+		Reason:     ReasonUnknown,
 	}
 )
 
@@ -27,6 +28,7 @@ func TestNewLokiClient(t *testing.T) {
 	require.NotNil(t, lokiClient)
 	require.Equal(t, url, lokiClient.url)
 	require.NotNil(t, lokiClient.client)
+	require.Equal(t, DefaultPushTimeout, lokiClient.timeout)
 }
 
 func TestLokiClient_WithHTTPClient(t *testing.T) {
@@ -73,12 +75,13 @@ func TestLokiClient_Push(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			t.Parallel()
 
-			sendError := uint(0)
+			sendError := 0
 			if testCase.expectedError != nil {
 				sendError = 1
 			}
 
-			fakeServer := fake.NewServer(sendError)
+			fakeServer := lokitest.NewServer()
+			fakeServer.FailNext(sendError)
 			httpServer := fakeServer.Start()
 
 			defer httpServer.Close()
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the Content-Encoding applied to the body of push requests, see [LokiClient.WithCompression].
+//
+// For the Loki push API, it is applied on top of the Snappy compression of the protobuf, since Loki removes the
+// Content-Encoding first and then always expects a Snappy-compressed protobuf. This allows proxies that require a
+// specific Content-Encoding to forward requests to Loki unchanged.
+type Compression string
+
+const (
+	// CompressionNone sends the body without a Content-Encoding. For the Loki push API, this is Loki's native format of
+	// a Snappy-compressed protobuf. It is the default.
+	CompressionNone Compression = ""
+	// CompressionGzip compresses the body using gzip. Loki accepts it directly.
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd compresses the body using zstd. Loki does not accept it directly, so it requires a gateway that
+	// decompresses requests before forwarding them to Loki.
+	CompressionZstd Compression = "zstd"
+)
+
+// ErrUnknownCompression is returned when pushing with a [Compression] that is not one of the defined constants.
+var ErrUnknownCompression = errors.New("unknown compression")
+
+var (
+	// gzipWriterPool holds gzip writers to reuse their internal state across pushes.
+	gzipWriterPool = sync.Pool{
+		New: func() any {
+			return gzip.NewWriter(nil)
+		},
+	}
+	// zstdEncoder is shared by all pushes, since EncodeAll is safe to call concurrently.
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+
+		return encoder
+	})
+)
+
+// ContentEncoding returns the value of the Content-Encoding header for the compression, which is empty for
+// [CompressionNone].
+func (compression Compression) ContentEncoding() string {
+	return string(compression)
+}
+
+// Compress appends the compressed data to dst and returns the result. For [CompressionNone], the data is appended
+// unchanged. It returns an error wrapping [ErrUnknownCompression] for unknown compressions.
+func (compression Compression) Compress(dst, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return append(dst, data...), nil
+	case CompressionGzip:
+		return compressGzip(dst, data)
+	case CompressionZstd:
+		return zstdEncoder().EncodeAll(data, dst), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCompression, string(compression))
+	}
+}
+
+// compressGzip appends the gzip-compressed data to dst using a pooled writer.
+func compressGzip(dst, data []byte) ([]byte, error) {
+	writer := gzipWriterPool.Get().(*gzip.Writer) //nolint:forcetypeassert // The pool only contains gzip writers.
+	defer func() {
+		// Do not keep the buffer alive through the pool.
+		writer.Reset(nil)
+		gzipWriterPool.Put(writer)
+	}()
+
+	buffer := &appendWriter{buf: dst}
+	writer.Reset(buffer)
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.buf, nil
+}
+
+// appendWriter is an [io.Writer] that appends to a byte slice, allowing compressors to write into reused buffers.
+type appendWriter struct {
+	buf []byte
+}
+
+func (writer *appendWriter) Write(data []byte) (int, error) {
+	writer.buf = append(writer.buf, data...)
+
+	return len(data), nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+func TestLokiClient_WithCompression(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		compression Compression
+		expected    string
+	}{
+		{name: "none", compression: CompressionNone, expected: ""},
+		{name: "gzip", compression: CompressionGzip, expected: "gzip"},
+		{name: "zstd", compression: CompressionZstd, expected: "zstd"},
+	}
+
+	entry := Entry{
+		Timestamp:          time.Now(),
+		Labels:             LabelMap{"foo": "bar"},
+		Line:               "test message",
+		StructuredMetadata: map[string]string{"key": "value"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			contentEncodings := make(chan string, 1)
+			httpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				contentEncodings <- request.Header.Get("Content-Encoding")
+
+				fakeServer.ServeHTTP(writer, request)
+			}))
+
+			defer httpServer.Close()
+
+			lokiClient := NewLokiClient(httpServer.URL + PushPath).WithCompression(testCase.compression)
+
+			// Pushing twice checks that the pooled buffers are reused correctly.
+			require.NoError(t, lokiClient.Push(t.Context(), entry))
+			require.Equal(t, testCase.expected, <-contentEncodings)
+			require.NoError(t, lokiClient.Push(t.Context(), entry))
+			require.Equal(t, testCase.expected, <-contentEncodings)
+
+			streams := fakeServer.Streams()
+			require.Len(t, streams, 2)
+			AssertStreamMatchesEntry(t, entry, streams[0])
+			AssertStreamMatchesEntry(t, entry, streams[1])
+		})
+	}
+}
+
+func TestLokiClient_UnknownCompression(t *testing.T) {
+	t.Parallel()
+
+	err := NewLokiClient("http://localhost"+PushPath).WithCompression("brotli").Push(t.Context(), Entry{})
+	require.ErrorIs(t, err, ErrUnknownCompression)
+}
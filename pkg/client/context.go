@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"maps"
+
+	"github.com/tslnc04/loki-logger/pkg/internal/labels"
+)
+
+// contextKey is the type of the keys used to store labels and metadata in a [context.Context].
+type contextKey int
+
+const (
+	// labelsContextKey is the key of the labels added using ContextWithLabels.
+	labelsContextKey contextKey = iota
+	// metadataContextKey is the key of the structured metadata added using ContextWithMetadata.
+	metadataContextKey
+)
+
+// ContextWithLabels returns a copy of the context carrying the given stream labels in addition to any labels already
+// carried by the context. The keys and values alternate, i.e. they are given as key1, value1, key2, value2, and so on.
+// If there are an odd number of keys and values, the last key is ignored.
+//
+// The labels are added to every entry pushed with the context by [LokiClient] and other clients using [MergeContext].
+// This allows attaching per-request labels, such as the tenant, without passing a logger around.
+func ContextWithLabels(ctx context.Context, keysAndValues ...string) context.Context {
+	return context.WithValue(ctx, labelsContextKey, withValues(LabelsFromContext(ctx), keysAndValues))
+}
+
+// ContextWithMetadata returns a copy of the context carrying the given structured metadata in addition to any metadata
+// already carried by the context. The keys and values are given the same way as for [ContextWithLabels].
+//
+// The metadata is added to every entry pushed with the context by [LokiClient] and other clients using [MergeContext].
+// This allows attaching per-request fields, such as a request or user ID, without creating a new stream for each value.
+func ContextWithMetadata(ctx context.Context, keysAndValues ...string) context.Context {
+	return context.WithValue(ctx, metadataContextKey, withValues(MetadataFromContext(ctx), keysAndValues))
+}
+
+// LabelsFromContext returns the stream labels carried by the context, or nil if there are none. The returned map must
+// not be modified.
+func LabelsFromContext(ctx context.Context) map[string]string {
+	contextLabels, _ := ctx.Value(labelsContextKey).(map[string]string)
+
+	return contextLabels
+}
+
+// MetadataFromContext returns the structured metadata carried by the context, or nil if there is none. The returned map
+// must not be modified.
+func MetadataFromContext(ctx context.Context) map[string]string {
+	metadata, _ := ctx.Value(metadataContextKey).(map[string]string)
+
+	return metadata
+}
+
+// MergeContext returns a copy of the entry with the labels and structured metadata carried by the context added. Keys
+// already present in the entry take precedence over those from the context. The entry itself is not modified.
+//
+// If the context carries labels and the entry's labels are not a [LabelMap], they are parsed from their string
+// representation. Should that fail, the labels from the context are not added.
+func MergeContext(ctx context.Context, entry Entry) Entry {
+	if contextLabels := LabelsFromContext(ctx); len(contextLabels) > 0 {
+		entry.Labels = mergeLabels(entry.Labels, contextLabels)
+	}
+
+	if metadata := MetadataFromContext(ctx); len(metadata) > 0 {
+		merged := maps.Clone(metadata)
+		maps.Copy(merged, entry.StructuredMetadata)
+		entry.StructuredMetadata = merged
+	}
+
+	return entry
+}
+
+// mergeLabels returns a LabelMap containing the context labels and the labels of the labeler, with the latter taking
+// precedence. If the labeler cannot be converted to a map, it is returned unchanged.
+func mergeLabels(labeler Labeler, contextLabels map[string]string) Labeler {
+	merged := maps.Clone(contextLabels)
+
+	switch typed := labeler.(type) {
+	case nil:
+	case LabelMap:
+		maps.Copy(merged, typed)
+	case Labels:
+		maps.Copy(merged, typed.Map())
+	default:
+		parsed, err := labels.Parse(string(labeler.Label()))
+		if err != nil {
+			return labeler
+		}
+
+		maps.Copy(merged, parsed)
+	}
+
+	return LabelMap(merged)
+}
+
+// withValues returns a new map with the existing values and the keys and values added. If there are an odd number of
+// keys and values, the last key is ignored.
+func withValues(existing map[string]string, keysAndValues []string) map[string]string {
+	values := make(map[string]string, len(existing)+len(keysAndValues)/2)
+	maps.Copy(values, existing)
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		values[keysAndValues[i]] = keysAndValues[i+1]
+	}
+
+	return values
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+func TestContextWithLabels(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, LabelsFromContext(t.Context()))
+
+	ctx := ContextWithLabels(t.Context(), "tenant", "a", "dangling")
+	nestedCtx := ContextWithLabels(ctx, "tenant", "b", "region", "eu")
+
+	require.Equal(t, map[string]string{"tenant": "a"}, LabelsFromContext(ctx))
+	require.Equal(t, map[string]string{"tenant": "b", "region": "eu"}, LabelsFromContext(nestedCtx))
+	require.Nil(t, MetadataFromContext(nestedCtx))
+}
+
+func TestContextWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	ctx := ContextWithMetadata(t.Context(), "request_id", "1")
+	nestedCtx := ContextWithMetadata(ctx, "user_id", "2")
+
+	require.Equal(t, map[string]string{"request_id": "1"}, MetadataFromContext(ctx))
+	require.Equal(t, map[string]string{"request_id": "1", "user_id": "2"}, MetadataFromContext(nestedCtx))
+	require.Nil(t, LabelsFromContext(nestedCtx))
+}
+
+//nolint:funlen // Most of the function is test cases, no need to worry about length.
+func TestMergeContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := ContextWithLabels(t.Context(), "tenant", "a", "app", "context")
+	ctx = ContextWithMetadata(ctx, "request_id", "1", "key", "context")
+
+	testCases := []struct {
+		name     string
+		entry    Entry
+		expected Entry
+	}{
+		{
+			name:  "nil-labels",
+			entry: Entry{Line: "test"},
+			expected: Entry{
+				Labels:             LabelMap{"tenant": "a", "app": "context"},
+				Line:               "test",
+				StructuredMetadata: map[string]string{"request_id": "1", "key": "context"},
+			},
+		},
+		{
+			name: "label-map",
+			entry: Entry{
+				Labels:             LabelMap{"app": "entry"},
+				StructuredMetadata: map[string]string{"key": "entry"},
+			},
+			expected: Entry{
+				Labels:             LabelMap{"tenant": "a", "app": "entry"},
+				StructuredMetadata: map[string]string{"request_id": "1", "key": "entry"},
+			},
+		},
+		{
+			name:  "label-string",
+			entry: Entry{Labels: LabelString(`{app="entry", level="info"}`)},
+			expected: Entry{
+				Labels:             LabelMap{"tenant": "a", "app": "entry", "level": "info"},
+				StructuredMetadata: map[string]string{"request_id": "1", "key": "context"},
+			},
+		},
+		{
+			name:  "labels",
+			entry: Entry{Labels: NewLabels(map[string]string{"app": "entry"})},
+			expected: Entry{
+				Labels:             LabelMap{"tenant": "a", "app": "entry"},
+				StructuredMetadata: map[string]string{"request_id": "1", "key": "context"},
+			},
+		},
+		{
+			name:  "invalid-label-string",
+			entry: Entry{Labels: LabelString(`invalid`)},
+			expected: Entry{
+				Labels:             LabelString(`invalid`),
+				StructuredMetadata: map[string]string{"request_id": "1", "key": "context"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testCase.expected, MergeContext(ctx, testCase.entry))
+		})
+	}
+}
+
+func TestMergeContext_Empty(t *testing.T) {
+	t.Parallel()
+
+	entry := Entry{Labels: LabelString(`{app="entry"}`), Line: "test"}
+	require.Equal(t, entry, MergeContext(t.Context(), entry))
+}
+
+func TestLokiClient_PushContext(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	ctx := ContextWithLabels(t.Context(), "tenant", "a")
+	ctx = ContextWithMetadata(ctx, "request_id", "1")
+
+	lokiClient := NewLokiClient(httpServer.URL + PushPath)
+	require.NoError(t, lokiClient.Push(ctx, Entry{Timestamp: time.Now(), Labels: LabelMap{"app": "test"}, Line: "test"}))
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 1, "Expected number of streams to match")
+	AssertStreamMatchesEntry(t, Entry{
+		Labels:             LabelMap{"app": "test", "tenant": "a"},
+		Line:               "test",
+		StructuredMetadata: map[string]string{"request_id": "1"},
+	}, streams[0])
+}
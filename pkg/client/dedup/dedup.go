@@ -0,0 +1,213 @@
+// Package dedup provides a thin wrapper around the [client.Client] interface that collapses repeated log lines into a
+// single entry.
+//
+// When a dependency fails, the same error is often logged many times a second. The [Client] in this package holds back
+// each entry for a window of time and counts identical entries, i.e. those with the same labels and line, that are
+// pushed in the meantime. Once the window closes, a single entry is pushed to the inner client, annotated with the
+// number of repetitions in the [RepeatCountKey] structured metadata field.
+package dedup
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tslnc04/loki-logger/pkg/client"
+)
+
+const (
+	// RepeatCountKey is the key added to the structured metadata of an entry that was repeated within the window. Its
+	// value is the total number of identical entries, including the first one.
+	RepeatCountKey = "repeat_count"
+	// DefaultWindow is the default window used by [Client] when none is provided.
+	DefaultWindow = time.Second
+)
+
+// Client is a client that collapses identical entries pushed within a window into a single entry. It implements the
+// [client.Client] interface and is safe to call concurrently from multiple goroutines.
+//
+// Only the first entry of a window is kept, so its timestamp and structured metadata are used for the collapsed entry.
+// The context of the first push is also used when pushing the collapsed entry, although without its cancellation, since
+// the original push has long returned by then.
+type Client struct {
+	inner   client.Client
+	window  time.Duration
+	lock    *sync.Mutex
+	pending map[pendingKey]*pendingEntry
+	// emitting tracks the entries pushed by their timers since the last flush. It is replaced by each flush, so that no
+	// more emits are added to the group that the flush waits for.
+	emitting *sync.WaitGroup
+}
+
+// pendingKey identifies entries that are considered identical.
+type pendingKey struct {
+	labels client.LabelString
+	line   string
+}
+
+// pendingEntry is an entry held back until its window closes.
+type pendingEntry struct {
+	// ctx is the context of the first push, only stored until the entry is pushed at the end of the window.
+	ctx   context.Context
+	entry client.Entry
+	count uint64
+	timer *time.Timer
+}
+
+// NewDedupClient creates a new Client wrapping the given client. It defaults to using [DefaultWindow].
+func NewDedupClient(inner client.Client) *Client {
+	return &Client{
+		inner:    inner,
+		window:   DefaultWindow,
+		lock:     &sync.Mutex{},
+		pending:  make(map[pendingKey]*pendingEntry),
+		emitting: &sync.WaitGroup{},
+	}
+}
+
+// WithWindow returns a new Client with the same inner client and the given window. Pending entries are not shared
+// between the Clients. A window of zero or less disables deduplication, pushing each entry immediately.
+func (dedupClient *Client) WithWindow(window time.Duration) *Client {
+	newClient := NewDedupClient(dedupClient.inner)
+	newClient.window = window
+
+	return newClient
+}
+
+// Assert that Client implements the [client.Client] and [client.Flusher] interfaces.
+var (
+	_ client.Client  = (*Client)(nil)
+	_ client.Flusher = (*Client)(nil)
+)
+
+// Push implements the [client.Client] interface. If an identical entry is already pending, it only counts the
+// repetition. Otherwise, the entry is held back until the window closes. It always returns nil unless deduplication is
+// disabled, since the actual push happens asynchronously.
+func (dedupClient *Client) Push(ctx context.Context, entry client.Entry) error {
+	if dedupClient.window <= 0 {
+		return dedupClient.inner.Push(ctx, entry)
+	}
+
+	key := pendingKey{labels: "{}", line: entry.Line}
+	if entry.Labels != nil {
+		key.labels = entry.Labels.Label()
+	}
+
+	dedupClient.lock.Lock()
+	defer dedupClient.lock.Unlock()
+
+	if pending, ok := dedupClient.pending[key]; ok {
+		pending.count++
+
+		return nil
+	}
+
+	pending := &pendingEntry{
+		ctx:   context.WithoutCancel(ctx),
+		entry: entry,
+		count: 1,
+	}
+	pending.timer = time.AfterFunc(dedupClient.window, func() {
+		dedupClient.emit(key, pending)
+	})
+	dedupClient.pending[key] = pending
+
+	return nil
+}
+
+// Flush immediately pushes all pending entries using the given context, regardless of how long their windows have
+// left, and waits for the entries already being pushed because their windows closed. If the inner client implements
+// [client.Flusher], it is flushed afterwards. It returns the joined errors of all pushes and the flush of the inner
+// client, or the error of the context if it is done while waiting.
+func (dedupClient *Client) Flush(ctx context.Context) error {
+	// The new group also waits for the previous one, so that a later flush waits for the emits of a concurrent one.
+	next := &sync.WaitGroup{}
+	next.Add(1)
+
+	dedupClient.lock.Lock()
+	pending := dedupClient.pending
+	emitting := dedupClient.emitting
+	dedupClient.pending = make(map[pendingKey]*pendingEntry)
+	dedupClient.emitting = next
+	dedupClient.lock.Unlock()
+
+	go func() {
+		emitting.Wait()
+		next.Done()
+	}()
+
+	errs := make([]error, 0, len(pending)+2)
+
+	for _, entry := range pending {
+		entry.timer.Stop()
+		errs = append(errs, dedupClient.inner.Push(ctx, entry.collapse()))
+	}
+
+	errs = append(errs, wait(ctx, emitting))
+
+	if flusher, ok := dedupClient.inner.(client.Flusher); ok {
+		errs = append(errs, flusher.Flush(ctx))
+	}
+
+	return errors.Join(errs...)
+}
+
+// emit pushes the pending entry with the given key once its window has closed. If the entry has already been flushed,
+// it does nothing, even if a new entry with the same key is pending. Errors are discarded as there is no caller left
+// to return them to.
+func (dedupClient *Client) emit(key pendingKey, pending *pendingEntry) {
+	dedupClient.lock.Lock()
+
+	if dedupClient.pending[key] != pending {
+		dedupClient.lock.Unlock()
+
+		return
+	}
+
+	delete(dedupClient.pending, key)
+
+	emitting := dedupClient.emitting
+	emitting.Add(1)
+	dedupClient.lock.Unlock()
+
+	defer emitting.Done()
+
+	_ = dedupClient.inner.Push(pending.ctx, pending.collapse())
+}
+
+// wait waits until all emits of the group are done or the context is done, returning the error of the context in the
+// latter case.
+func wait(ctx context.Context, group *sync.WaitGroup) error {
+	done := make(chan struct{})
+
+	go func() {
+		group.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// collapse returns the entry to push for the window. If the entry was repeated, the repeat count is added to a copy of
+// its structured metadata.
+func (pending *pendingEntry) collapse() client.Entry {
+	entry := pending.entry
+	if pending.count <= 1 {
+		return entry
+	}
+
+	metadata := make(map[string]string, len(entry.StructuredMetadata)+1)
+	maps.Copy(metadata, entry.StructuredMetadata)
+	metadata[RepeatCountKey] = strconv.FormatUint(pending.count, 10)
+	entry.StructuredMetadata = metadata
+
+	return entry
+}
@@ -0,0 +1,190 @@
+package dedup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+var (
+	testEntry = client.Entry{
+		Timestamp:          time.Now(),
+		Labels:             client.LabelMap{"foo": "bar"},
+		Line:               "test message",
+		StructuredMetadata: map[string]string{"key": "value"},
+	}
+	otherEntry = client.Entry{
+		Timestamp: time.Now(),
+		Labels:    client.LabelMap{"foo": "baz"},
+		Line:      "test message",
+	}
+)
+
+func TestDedupClient_WithWindow(t *testing.T) {
+	t.Parallel()
+
+	dedupClient := NewDedupClient(nil)
+	require.Equal(t, DefaultWindow, dedupClient.window)
+
+	windowed := dedupClient.WithWindow(time.Minute)
+	require.Equal(t, time.Minute, windowed.window)
+
+	// The original client should not be modified.
+	require.Equal(t, DefaultWindow, dedupClient.window)
+}
+
+func TestDedupClient_Push(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		window time.Duration
+		wait   time.Duration
+	}{
+		{
+			name:   "window-closes",
+			window: 50 * time.Millisecond,
+			wait:   200 * time.Millisecond,
+		},
+		{
+			name:   "disabled",
+			window: 0,
+			wait:   0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+			dedupClient := NewDedupClient(lokiClient).WithWindow(testCase.window)
+
+			for range 3 {
+				require.NoError(t, dedupClient.Push(t.Context(), testEntry))
+			}
+
+			require.NoError(t, dedupClient.Push(t.Context(), otherEntry))
+
+			time.Sleep(testCase.wait)
+
+			streams := fakeServer.Streams()
+
+			if testCase.window == 0 {
+				require.Len(t, streams, 4, "Expected every entry to be sent")
+
+				return
+			}
+
+			require.Len(t, streams, 2, "Expected identical entries to be collapsed")
+
+			expected := testEntry
+			expected.StructuredMetadata = map[string]string{"key": "value", RepeatCountKey: "3"}
+
+			for _, stream := range streams {
+				if stream.Labels == string(otherEntry.Labels.Label()) {
+					client.AssertStreamMatchesEntry(t, otherEntry, stream)
+				} else {
+					client.AssertStreamMatchesEntry(t, expected, stream)
+				}
+			}
+
+			// The metadata of the pushed entry should not be modified.
+			require.Len(t, testEntry.StructuredMetadata, 1)
+		})
+	}
+}
+
+func TestDedupClient_Flush(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	dedupClient := NewDedupClient(lokiClient).WithWindow(time.Hour)
+
+	require.NoError(t, dedupClient.Push(t.Context(), testEntry))
+	require.NoError(t, dedupClient.Push(t.Context(), otherEntry))
+	require.NoError(t, dedupClient.Flush(t.Context()))
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 2, "Expected pending entries to be flushed")
+	require.Empty(t, dedupClient.pending)
+}
+
+// blockingClient is a client whose pushes block until release is closed, reporting each entry on started first.
+type blockingClient struct {
+	started chan client.Entry
+	release chan struct{}
+}
+
+func (blocking *blockingClient) Push(_ context.Context, entry client.Entry) error {
+	blocking.started <- entry
+	<-blocking.release
+
+	return nil
+}
+
+func TestDedupClient_FlushWaitsForEmits(t *testing.T) {
+	t.Parallel()
+
+	inner := &blockingClient{started: make(chan client.Entry, 1), release: make(chan struct{})}
+	dedupClient := NewDedupClient(inner).WithWindow(time.Millisecond)
+
+	require.NoError(t, dedupClient.Push(t.Context(), testEntry))
+	require.Equal(t, testEntry, <-inner.started, "Expected the window to close")
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, dedupClient.Flush(ctx), context.DeadlineExceeded)
+
+	flushed := make(chan error, 1)
+
+	go func() {
+		flushed <- dedupClient.Flush(t.Context())
+	}()
+
+	require.Never(t, func() bool { return len(flushed) > 0 }, 50*time.Millisecond, 5*time.Millisecond)
+	close(inner.release)
+	require.NoError(t, <-flushed)
+}
+
+func TestDedupClient_EmitAfterFlush(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	dedupClient := NewDedupClient(lokiClient).WithWindow(time.Hour)
+	key := pendingKey{labels: testEntry.Labels.Label(), line: testEntry.Line}
+
+	require.NoError(t, dedupClient.Push(t.Context(), testEntry))
+
+	flushed := dedupClient.pending[key]
+
+	require.NoError(t, dedupClient.Flush(t.Context()))
+	require.NoError(t, dedupClient.Push(t.Context(), testEntry))
+
+	// The timer of the flushed entry may fire after the flush, which must not push the new entry early.
+	dedupClient.emit(key, flushed)
+
+	require.Len(t, fakeServer.Streams(), 1)
+	require.Contains(t, dedupClient.pending, key)
+}
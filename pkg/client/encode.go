@@ -0,0 +1,144 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/klauspost/compress/snappy"
+)
+
+// maxPooledBufferSize is the maximum capacity of a buffer that is returned to the pool. Larger buffers, e.g. from an
+// unusually long line, are left to the garbage collector so that they do not stay in memory indefinitely.
+const maxPooledBufferSize = 1 << 20
+
+// encoderPool holds encoders to reuse their buffers across pushes.
+var encoderPool = sync.Pool{
+	New: func() any {
+		return &encoder{
+			request: push.PushRequest{Streams: make([]push.Stream, 1)},
+			entries: make([]push.Entry, 1),
+		}
+	},
+}
+
+// encoder encodes entries into reusable buffers. It is reference counted, since the HTTP transport may still read the
+// request body after the response has been received. It is returned to the pool once all references are released.
+type encoder struct {
+	request    push.PushRequest
+	entries    []push.Entry
+	metadata   push.LabelsAdapter
+	marshaled  []byte
+	encoded    []byte
+	compressed []byte
+	// result is the body of the request, either encoded or compressed.
+	result []byte
+	refs   atomic.Int32
+}
+
+// newEncoder returns an encoder from the pool with a single reference, which must be released by the caller.
+func newEncoder() *encoder {
+	enc := encoderPool.Get().(*encoder) //nolint:forcetypeassert // The pool only contains encoders.
+	enc.refs.Store(1)
+
+	return enc
+}
+
+// encode serializes the entry to a protobuf and compresses it using Snappy, the same way as [Entry.Encode], followed by
+// the given compression. The result is only valid until the encoder is released.
+func (enc *encoder) encode(entry *Entry, compression Compression) ([]byte, error) {
+	enc.metadata = appendMetadata(enc.metadata[:0], entry.StructuredMetadata)
+
+	enc.entries[0] = push.Entry{
+		Timestamp:          entry.Timestamp,
+		Line:               entry.Line,
+		StructuredMetadata: enc.metadata,
+	}
+
+	labels := "{}"
+	if entry.Labels != nil {
+		labels = string(entry.Labels.Label())
+	}
+
+	enc.request.Streams[0] = push.Stream{
+		Labels:  labels,
+		Entries: enc.entries,
+	}
+
+	size := enc.request.Size()
+	enc.marshaled = grow(enc.marshaled, size)
+
+	if _, err := enc.request.MarshalToSizedBuffer(enc.marshaled); err != nil {
+		return nil, err
+	}
+
+	enc.encoded = grow(enc.encoded, snappy.MaxEncodedLen(size))
+	enc.encoded = snappy.Encode(enc.encoded, enc.marshaled)
+	enc.result = enc.encoded
+
+	if compression != CompressionNone {
+		compressed, err := compression.Compress(enc.compressed[:0], enc.encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		enc.compressed = compressed
+		enc.result = compressed
+	}
+
+	return enc.result, nil
+}
+
+// body returns a new request body reading the encoded entry. It holds a reference to the encoder until it is closed.
+func (enc *encoder) body() io.ReadCloser {
+	enc.refs.Add(1)
+
+	return &encoderBody{Reader: bytes.NewReader(enc.result), encoder: enc}
+}
+
+// release releases a reference to the encoder, returning it to the pool once no references are left.
+func (enc *encoder) release() {
+	if enc.refs.Add(-1) > 0 {
+		return
+	}
+
+	// Do not keep the entry alive through the pool.
+	clear(enc.metadata)
+	enc.entries[0] = push.Entry{}
+	enc.request.Streams[0] = push.Stream{}
+	enc.result = nil
+
+	if max(cap(enc.marshaled), cap(enc.encoded), cap(enc.compressed)) > maxPooledBufferSize {
+		return
+	}
+
+	encoderPool.Put(enc)
+}
+
+// encoderBody is a request body that releases its reference to the encoder when closed.
+type encoderBody struct {
+	*bytes.Reader
+
+	encoder *encoder
+	closed  atomic.Bool
+}
+
+// Close releases the reference to the encoder. It is safe to call multiple times.
+func (body *encoderBody) Close() error {
+	if body.closed.CompareAndSwap(false, true) {
+		body.encoder.release()
+	}
+
+	return nil
+}
+
+// grow returns the buffer resliced to the given length, only allocating if its capacity is too small.
+func grow(buf []byte, length int) []byte {
+	if cap(buf) < length {
+		return make([]byte, length)
+	}
+
+	return buf[:length]
+}
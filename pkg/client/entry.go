@@ -1,14 +1,12 @@
 package client
 
 import (
+	"bytes"
 	"slices"
-	"strconv"
-	"strings"
 	"time"
 
-	"github.com/gogo/protobuf/proto"
 	"github.com/grafana/loki/pkg/push"
-	"github.com/klauspost/compress/snappy"
+	"github.com/tslnc04/loki-logger/pkg/internal/labels"
 )
 
 // Labeler is an interface that abstracts the conversion of labels to a string for sending to Loki. For now, it is best
@@ -31,7 +29,7 @@ var _ Labeler = (*LabelMap)(nil)
 
 // Label returns the string representation of the LabelMap.
 func (lm LabelMap) Label() LabelString {
-	return LabelString(labelsToString(lm))
+	return LabelString(labels.Format(lm))
 }
 
 // LabelString is a string that contains labels already formatted as a string. It implements the [Labeler] interface.
@@ -67,7 +65,7 @@ func (entry *Entry) AsPushRequest() push.PushRequest {
 				Entries: []push.Entry{{
 					Timestamp:          entry.Timestamp,
 					Line:               entry.Line,
-					StructuredMetadata: metadataToLabelsAdapter(entry.StructuredMetadata),
+					StructuredMetadata: appendMetadata(nil, entry.StructuredMetadata),
 				}},
 			},
 		},
@@ -76,61 +74,30 @@ func (entry *Entry) AsPushRequest() push.PushRequest {
 
 // Encode converts the Entry to a byte slice that can be sent to Loki. It first serializes the Entry to a protobuf and
 // then encodes it using Snappy compression. This method does not modify the Entry.
+//
+// The intermediate buffers are pooled, so only the returned slice is allocated. [LokiClient] avoids even that by
+// writing the pooled buffer directly into the request body.
 func (entry *Entry) Encode() ([]byte, error) {
-	pushRequest := entry.AsPushRequest()
+	enc := newEncoder()
+	defer enc.release()
 
-	buf, err := proto.Marshal(&pushRequest)
+	buf, err := enc.encode(entry, CompressionNone)
 	if err != nil {
 		return nil, err
 	}
 
-	buf = snappy.Encode(nil, buf)
-
-	return buf, nil
-}
-
-// labelsToString converts a map of labels to a string that can be added to a stream. It follows the format required by
-// Loki and thus the [Labeler] interface. It does not modify the labels map.
-func labelsToString(labels map[string]string) string {
-	// This code is based heavily on the labelsMapToString function in the Promtail client, which is licensed under
-	// the Apache 2.0 license.
-	builder := strings.Builder{}
-	totalSize := 2
-	keys := make([]string, 0, len(labels))
-
-	for key, value := range labels {
-		keys = append(keys, key)
-		// add 2 for `, ` between labels and 3 for `=` and quotes around the value
-		totalSize += len(key) + 2 + len(value) + 3
-	}
-
-	builder.Grow(totalSize)
-	builder.WriteByte('{')
-	slices.Sort(keys)
-
-	for i, key := range keys {
-		if i > 0 {
-			builder.WriteString(", ")
-		}
-
-		builder.WriteString(key)
-		builder.WriteByte('=')
-		builder.WriteString(strconv.Quote(labels[key]))
-	}
-
-	builder.WriteByte('}')
-
-	return builder.String()
+	return bytes.Clone(buf), nil
 }
 
-// metadataToLabelsAdapter converts the map of structured metadata to a slice of [push.LabelAdapter] that can be added
-// to a stream. It does not modify the metadata map.
-func metadataToLabelsAdapter(metadata map[string]string) push.LabelsAdapter {
+// appendMetadata appends the structured metadata to the slice of [push.LabelAdapter] that can be added to a stream. If
+// there is no metadata, the slice is returned unchanged, so appending to nil results in nil. It does not modify the
+// metadata map.
+func appendMetadata(labels push.LabelsAdapter, metadata map[string]string) push.LabelsAdapter {
 	if len(metadata) == 0 {
-		return nil
+		return labels
 	}
 
-	labels := make([]push.LabelAdapter, 0, len(metadata))
+	labels = slices.Grow(labels, len(metadata))
 
 	for key, value := range metadata {
 		labels = append(labels, push.LabelAdapter{
@@ -1,10 +1,13 @@
 package client
 
 import (
+	"io"
 	"testing"
 	"time"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/grafana/loki/pkg/push"
+	"github.com/klauspost/compress/snappy"
 	"github.com/stretchr/testify/require"
 )
 
@@ -92,3 +95,127 @@ func TestEntry_AsPushRequest(t *testing.T) {
 		})
 	}
 }
+
+// benchmarkEntry is a typical entry with a few labels and some structured metadata.
+var benchmarkEntry = Entry{
+	Timestamp:          testTimestamp,
+	Labels:             LabelString(`{app="bench", env="test", level="info"}`),
+	Line:               "request handled successfully in 12ms",
+	StructuredMetadata: map[string]string{"request_id": "0123456789abcdef", "user_id": "42"},
+}
+
+// decodeEntry decodes a push request encoded by [Entry.Encode].
+func decodeEntry(t *testing.T, buf []byte) push.PushRequest {
+	t.Helper()
+
+	decoded, err := snappy.Decode(nil, buf)
+	require.NoError(t, err)
+
+	var pushRequest push.PushRequest
+	require.NoError(t, proto.Unmarshal(decoded, &pushRequest))
+
+	return pushRequest
+}
+
+func TestEntry_Encode(t *testing.T) {
+	t.Parallel()
+
+	entries := []Entry{
+		benchmarkEntry,
+		{Timestamp: testTimestamp, Labels: LabelMap{"foo": "bar"}, Line: "no metadata"},
+		{Timestamp: testTimestamp, Labels: LabelMap{}},
+	}
+
+	// Encoding several entries in a row reuses the pooled buffers, which must not leak between entries.
+	for _, entry := range entries {
+		buf, err := entry.Encode()
+		require.NoError(t, err)
+
+		pushRequest := decodeEntry(t, buf)
+		require.Len(t, pushRequest.Streams, 1)
+		AssertStreamMatchesEntry(t, entry, pushRequest.Streams[0])
+	}
+}
+
+func TestEncoder_Body(t *testing.T) {
+	t.Parallel()
+
+	enc := newEncoder()
+
+	buf, err := enc.encode(&benchmarkEntry, CompressionNone)
+	require.NoError(t, err)
+
+	firstBody := enc.body()
+	secondBody := enc.body()
+
+	// Releasing the caller's reference keeps the buffer alive for the bodies.
+	enc.release()
+
+	read, err := io.ReadAll(firstBody)
+	require.NoError(t, err)
+	require.Equal(t, buf, read)
+	require.NoError(t, firstBody.Close())
+	require.NoError(t, firstBody.Close())
+	require.Equal(t, int32(1), enc.refs.Load(), "Expected closing a body twice to release a single reference")
+
+	read, err = io.ReadAll(secondBody)
+	require.NoError(t, err)
+	require.Equal(t, buf, read)
+	require.NoError(t, secondBody.Close())
+	require.Zero(t, enc.refs.Load())
+}
+
+// BenchmarkEntry_EncodeUnpooled measures the encoding without pooled buffers for comparison.
+func BenchmarkEntry_EncodeUnpooled(b *testing.B) {
+	b.ReportAllocs()
+
+	for b.Loop() {
+		pushRequest := benchmarkEntry.AsPushRequest()
+
+		buf, err := proto.Marshal(&pushRequest)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		_ = snappy.Encode(nil, buf)
+	}
+}
+
+func BenchmarkEntry_Encode(b *testing.B) {
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, err := benchmarkEntry.Encode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkEncoder measures the encoding used by [LokiClient.Push], which writes the pooled buffer to the request, for
+// each compression. It also reports the size of the request body.
+func BenchmarkEncoder(b *testing.B) {
+	compressions := map[string]Compression{"none": CompressionNone, "gzip": CompressionGzip, "zstd": CompressionZstd}
+
+	for name, compression := range compressions {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			size := 0
+
+			for b.Loop() {
+				enc := newEncoder()
+
+				buf, err := enc.encode(&benchmarkEntry, compression)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				size = len(buf)
+
+				enc.release()
+			}
+
+			b.ReportMetric(float64(size), "bytes/body")
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/tslnc04/loki-logger/pkg/internal/labels"
+)
+
+// ErrInvalidLabels is returned when a label string cannot be parsed.
+var ErrInvalidLabels = labels.ErrSyntax
+
+// Label is a single stream label.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Labels is a set of stream labels sorted by name, with each name occurring at most once. Unlike a [LabelMap], it can
+// be looked up, compared, and hashed without formatting it first. It implements the [Labeler] interface.
+//
+// Labels should be created using [NewLabels] or [ParseLabels] to ensure that they are sorted. They should not be
+// modified afterwards, since methods such as [Labels.Merge] may share the underlying array.
+type Labels []Label
+
+var _ Labeler = Labels(nil)
+
+// NewLabels creates Labels from a map of labels. It does not modify the map.
+func NewLabels(labelMap map[string]string) Labels {
+	result := make(Labels, 0, len(labelMap))
+	for name, value := range labelMap {
+		result = append(result, Label{Name: name, Value: value})
+	}
+
+	slices.SortFunc(result, compareLabels)
+
+	return result
+}
+
+// ParseLabels parses a label string in the format produced by [Labeler.Label], such as `{a="b", c="d"}`, into Labels.
+// Values may be quoted using double quotes, single quotes, or backticks and use any of the escape sequences of Go
+// strings, like in Prometheus and LogQL. If a name occurs multiple times, the last value is used. It returns an error
+// wrapping [ErrInvalidLabels] if the string cannot be parsed.
+//
+// Formatting the result using [Labels.Label] results in the same string for any labels formatted by this package.
+func ParseLabels(input LabelString) (Labels, error) {
+	parsed, err := labels.Parse(string(input))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLabels(parsed), nil
+}
+
+// Label returns the string representation of the Labels, in the same format as for a [LabelMap].
+func (labelSet Labels) Label() LabelString {
+	return LabelString(labels.FormatSorted(func(yield func(string, string) bool) {
+		for _, label := range labelSet {
+			if !yield(label.Name, label.Value) {
+				return
+			}
+		}
+	}))
+}
+
+// Get returns the value of the label with the given name, or an empty string if there is none.
+func (labelSet Labels) Get(name string) string {
+	if index, ok := labelSet.find(name); ok {
+		return labelSet[index].Value
+	}
+
+	return ""
+}
+
+// Has reports whether there is a label with the given name.
+func (labelSet Labels) Has(name string) bool {
+	_, ok := labelSet.find(name)
+
+	return ok
+}
+
+// Merge returns the union of the Labels and the other labels. If a name occurs in both, the value of the other labels
+// is used. Neither of the labels is modified.
+func (labelSet Labels) Merge(other Labels) Labels {
+	switch {
+	case len(other) == 0:
+		return labelSet
+	case len(labelSet) == 0:
+		return other
+	}
+
+	merged := make(Labels, 0, len(labelSet)+len(other))
+	index, otherIndex := 0, 0
+
+	for index < len(labelSet) && otherIndex < len(other) {
+		switch compareLabels(labelSet[index], other[otherIndex]) {
+		case -1:
+			merged = append(merged, labelSet[index])
+			index++
+		case 1:
+			merged = append(merged, other[otherIndex])
+			otherIndex++
+		default:
+			merged = append(merged, other[otherIndex])
+			index++
+			otherIndex++
+		}
+	}
+
+	merged = append(merged, labelSet[index:]...)
+	merged = append(merged, other[otherIndex:]...)
+
+	return merged
+}
+
+// Hash returns a hash of the labels, which is the same for equal labels regardless of how they were created. It uses
+// 64-bit FNV-1a and is stable across processes, so it can be used for sharding and routing. Different labels may still
+// result in the same hash.
+func (labelSet Labels) Hash() uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+
+	hash := uint64(offset64)
+	writeByte := func(value byte) {
+		hash ^= uint64(value)
+		hash *= prime64
+	}
+
+	// Each string is prefixed with its length, so that different labels cannot produce the same input to the hash.
+	// Values may contain any bytes, so a separator would not be enough.
+	write := func(value string) {
+		for shift := 0; shift < 64; shift += 8 {
+			writeByte(byte(uint64(len(value)) >> shift))
+		}
+
+		for i := range len(value) {
+			writeByte(value[i])
+		}
+	}
+
+	for _, label := range labelSet {
+		write(label.Name)
+		write(label.Value)
+	}
+
+	return hash
+}
+
+// Map returns the labels as a [LabelMap].
+func (labelSet Labels) Map() LabelMap {
+	labelMap := make(LabelMap, len(labelSet))
+	for _, label := range labelSet {
+		labelMap[label.Name] = label.Value
+	}
+
+	return labelMap
+}
+
+// find returns the index of the label with the given name and whether it exists.
+func (labelSet Labels) find(name string) (int, bool) {
+	return slices.BinarySearchFunc(labelSet, name, func(label Label, name string) int {
+		return cmp.Compare(label.Name, name)
+	})
+}
+
+// compareLabels orders labels by name.
+func compareLabels(a, b Label) int {
+	return cmp.Compare(a.Name, b.Name)
+}
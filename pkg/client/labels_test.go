@@ -0,0 +1,105 @@
+package client
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// labelNamePattern matches valid label names, which are the only names that round-trip through a label string.
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func TestParseLabels(t *testing.T) {
+	t.Parallel()
+
+	parsed, err := ParseLabels(`{level='warn', app="test\u00e9", path=` + "`C:\\logs`" + `}`)
+	require.NoError(t, err)
+	require.Equal(t, Labels{{"app", "testé"}, {"level", "warn"}, {"path", `C:\logs`}}, parsed)
+	require.Equal(t, LabelString(`{app="testé", level="warn", path="C:\\logs"}`), parsed.Label())
+
+	empty, err := ParseLabels("{}")
+	require.NoError(t, err)
+	require.Empty(t, empty)
+	require.Equal(t, LabelString("{}"), empty.Label())
+
+	_, err = ParseLabels(`{app="test}`)
+	require.ErrorIs(t, err, ErrInvalidLabels)
+}
+
+func TestLabels(t *testing.T) {
+	t.Parallel()
+
+	labelSet := NewLabels(map[string]string{"app": "test", "level": "info", "empty": ""})
+
+	require.Equal(t, "test", labelSet.Get("app"))
+	require.Empty(t, labelSet.Get("missing"))
+	require.True(t, labelSet.Has("empty"))
+	require.False(t, labelSet.Has("missing"))
+	require.Equal(t, LabelMap{"app": "test", "level": "info", "empty": ""}, labelSet.Map())
+	require.Equal(t, LabelMap{"app": "test", "level": "info", "empty": ""}.Label(), labelSet.Label())
+
+	merged := labelSet.Merge(NewLabels(map[string]string{"level": "error", "region": "eu", "a": "1"}))
+	require.Equal(t, Labels{{"a", "1"}, {"app", "test"}, {"empty", ""}, {"level", "error"}, {"region", "eu"}}, merged)
+	require.Equal(t, "info", labelSet.Get("level"), "Expected merge not to modify the labels")
+	require.Equal(t, labelSet, labelSet.Merge(nil))
+	require.Equal(t, labelSet, Labels(nil).Merge(labelSet))
+
+	require.Equal(t, labelSet.Hash(), NewLabels(labelSet.Map()).Hash())
+	require.NotEqual(t, labelSet.Hash(), merged.Hash())
+	require.NotEqual(t, NewLabels(map[string]string{"a": "bc"}).Hash(), NewLabels(map[string]string{"ab": "c"}).Hash())
+
+	// Values may contain any bytes, including the separator used by Prometheus.
+	separated, err := ParseLabels(`{a="x\xffb\xff"}`)
+	require.NoError(t, err)
+	require.Equal(t, Labels{{"a", "x\xffb\xff"}}, separated)
+	require.NotEqual(t, Labels{{"a", "x"}, {"b", ""}}.Hash(), separated.Hash())
+}
+
+func FuzzParseLabels_RoundTrip(f *testing.F) {
+	f.Add("app", "test", "level", "info")
+	f.Add("a", "quote\"and\\backslash", "b", "newline\n\ttab")
+	f.Add("_", "\x00\xff invalid utf-8", "z9", "unicode \u00e9\U0001f600")
+	f.Add("a", "}", "b", `{c="d"}, e=`)
+
+	f.Fuzz(func(t *testing.T, name1, value1, name2, value2 string) {
+		if !labelNamePattern.MatchString(name1) || !labelNamePattern.MatchString(name2) {
+			t.Skip()
+		}
+
+		labelMap := LabelMap{name1: value1, name2: value2}
+
+		parsed, err := ParseLabels(labelMap.Label())
+		require.NoError(t, err)
+		require.Equal(t, labelMap, parsed.Map())
+		require.Equal(t, labelMap.Label(), parsed.Label())
+		require.Equal(t, NewLabels(labelMap).Hash(), parsed.Hash())
+	})
+}
+
+func FuzzParseLabels(f *testing.F) {
+	f.Add(`{app="test", level="info"}`)
+	f.Add(`{a='b\'c', d=` + "`e\\f`" + `}`)
+	f.Add(`{a="\x00\u00e9\U0001f600"}`)
+	f.Add(`{a="1" b="2"}`)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		parsed, err := ParseLabels(LabelString(input))
+		if err != nil {
+			require.ErrorIs(t, err, ErrInvalidLabels)
+
+			return
+		}
+
+		// Parsing the formatted labels again must result in the same labels, as long as the names are valid.
+		for _, label := range parsed {
+			if !labelNamePattern.MatchString(label.Name) {
+				t.Skip()
+			}
+		}
+
+		reparsed, err := ParseLabels(parsed.Label())
+		require.NoError(t, err)
+		require.Equal(t, parsed, reparsed)
+	})
+}
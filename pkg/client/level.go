@@ -0,0 +1,145 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DefaultLevelKey is the stream label that adapters add the level of a log line to by default.
+const DefaultLevelKey = "level"
+
+// Names of the levels used by [DefaultLevelMapper]. These are the values that Grafana recognizes when detecting the
+// level of a log line, e.g. for the log volume panel.
+const (
+	LevelNameTrace    = "trace"
+	LevelNameDebug    = "debug"
+	LevelNameInfo     = "info"
+	LevelNameWarn     = "warn"
+	LevelNameError    = "error"
+	LevelNameCritical = "critical"
+)
+
+// Levels on the [slog.Level] scale that have no counterpart in the slog package. Adapters for other logging libraries
+// use them for their trace, fatal, and panic levels.
+const (
+	// LevelTrace is the level of trace messages, below [slog.LevelDebug].
+	LevelTrace slog.Level = -8
+	// LevelCritical is the level of fatal and panic messages, above [slog.LevelError].
+	LevelCritical slog.Level = 12
+)
+
+// ErrUnknownLevel is returned by [ParseLevel] if the name does not correspond to a level.
+var ErrUnknownLevel = errors.New("unknown level")
+
+// LevelMapper converts a level on the [slog.Level] scale to the value of the level label. Adapters for other logging
+// libraries convert their levels to the slog scale first, e.g. the logr adapter maps V(n) to slog.Level(-n).
+type LevelMapper func(level slog.Level) string
+
+// DefaultLevelMapper maps levels to the lowercase names recognized by Grafana. Each name covers the levels from its own
+// up to, but not including, the next one, so slog.LevelInfo+2 is "info" and anything below [slog.LevelDebug] is
+// "trace".
+func DefaultLevelMapper(level slog.Level) string {
+	switch {
+	case level < slog.LevelDebug:
+		return LevelNameTrace
+	case level < slog.LevelInfo:
+		return LevelNameDebug
+	case level < slog.LevelWarn:
+		return LevelNameInfo
+	case level < slog.LevelError:
+		return LevelNameWarn
+	case level < LevelCritical:
+		return LevelNameError
+	default:
+		return LevelNameCritical
+	}
+}
+
+// NewLevelMapper creates a LevelMapper from the given names by level. Like [DefaultLevelMapper], each name covers the
+// levels from its own up to the next configured level. Levels below the lowest configured level use its name. If names
+// is empty, DefaultLevelMapper is returned.
+func NewLevelMapper(names map[slog.Level]string) LevelMapper {
+	if len(names) == 0 {
+		return DefaultLevelMapper
+	}
+
+	levels := slices.Sorted(maps.Keys(names))
+
+	return func(level slog.Level) string {
+		name := names[levels[0]]
+
+		for _, threshold := range levels[1:] {
+			if level < threshold {
+				break
+			}
+
+			name = names[threshold]
+		}
+
+		return name
+	}
+}
+
+// ParseLevel parses the name of a level as produced by [DefaultLevelMapper] or [slog.Level.String], ignoring case.
+// Common aliases such as "warning" and "fatal" are accepted as well. It returns [ErrUnknownLevel] if the name is not
+// recognized.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case LevelNameTrace:
+		return LevelTrace, nil
+	case "warning":
+		return slog.LevelWarn, nil
+	case LevelNameCritical, "fatal", "panic", "dpanic":
+		return LevelCritical, nil
+	}
+
+	var level slog.Level
+
+	err := level.UnmarshalText([]byte(name))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownLevel, name)
+	}
+
+	return level, nil
+}
+
+// LevelLabel configures how adapters add the level of a log line to the stream labels. The zero value adds no level
+// label at all, so use [DefaultLevelLabel] as the starting point for customizations.
+type LevelLabel struct {
+	// Key is the key of the level label, e.g. [DefaultLevelKey] or "detected_level". If it is empty, no level label is
+	// added.
+	Key string
+	// Mapper converts the level to the value of the label. If it is nil, [DefaultLevelMapper] is used.
+	Mapper LevelMapper
+}
+
+// DefaultLevelLabel returns the LevelLabel used by the adapters unless configured otherwise. It uses
+// [DefaultLevelKey] and leaves the mapper unset, so [DefaultLevelMapper] is used.
+func DefaultLevelLabel() LevelLabel {
+	return LevelLabel{
+		Key: DefaultLevelKey,
+	}
+}
+
+// Name returns the value of the label for the given level.
+func (levelLabel LevelLabel) Name(level slog.Level) string {
+	if levelLabel.Mapper == nil {
+		return DefaultLevelMapper(level)
+	}
+
+	return levelLabel.Mapper(level)
+}
+
+// Apply adds the level label for the given level to the labels, overwriting any existing value. It does nothing if the
+// key is empty.
+func (levelLabel LevelLabel) Apply(labels map[string]string, level slog.Level) {
+	if levelLabel.Key == "" {
+		return
+	}
+
+	labels[levelLabel.Key] = levelLabel.Name(level)
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultLevelMapper(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		level    slog.Level
+		expected string
+	}{
+		{level: LevelTrace - 4, expected: LevelNameTrace},
+		{level: LevelTrace, expected: LevelNameTrace},
+		{level: slog.LevelDebug, expected: LevelNameDebug},
+		{level: slog.LevelDebug + 2, expected: LevelNameDebug},
+		{level: slog.LevelInfo, expected: LevelNameInfo},
+		{level: slog.LevelWarn, expected: LevelNameWarn},
+		{level: slog.LevelError, expected: LevelNameError},
+		{level: LevelCritical, expected: LevelNameCritical},
+		{level: LevelCritical + 4, expected: LevelNameCritical},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.level.String(), func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testCase.expected, DefaultLevelMapper(testCase.level))
+		})
+	}
+}
+
+func TestNewLevelMapper(t *testing.T) {
+	t.Parallel()
+
+	mapper := NewLevelMapper(map[slog.Level]string{
+		slog.LevelInfo:  "INFO",
+		slog.LevelError: "ERR",
+	})
+
+	require.Equal(t, "INFO", mapper(slog.LevelDebug))
+	require.Equal(t, "INFO", mapper(slog.LevelWarn))
+	require.Equal(t, "ERR", mapper(slog.LevelError))
+	require.Equal(t, "ERR", mapper(LevelCritical))
+
+	require.Equal(t, LevelNameWarn, NewLevelMapper(nil)(slog.LevelWarn))
+}
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		expected slog.Level
+		err      bool
+	}{
+		{name: "trace", expected: LevelTrace},
+		{name: "DEBUG", expected: slog.LevelDebug},
+		{name: "info", expected: slog.LevelInfo},
+		{name: "INFO+2", expected: slog.LevelInfo + 2},
+		{name: "warning", expected: slog.LevelWarn},
+		{name: "error", expected: slog.LevelError},
+		{name: "fatal", expected: LevelCritical},
+		{name: "critical", expected: LevelCritical},
+		{name: "verbose", err: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			level, err := ParseLevel(testCase.name)
+			if testCase.err {
+				require.ErrorIs(t, err, ErrUnknownLevel)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, level)
+		})
+	}
+}
+
+func TestLevelLabel_Apply(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{}
+
+	DefaultLevelLabel().Apply(labels, slog.LevelWarn)
+	require.Equal(t, map[string]string{DefaultLevelKey: LevelNameWarn}, labels)
+
+	LevelLabel{Key: "detected_level"}.Apply(labels, slog.LevelError)
+	require.Equal(t, map[string]string{DefaultLevelKey: LevelNameWarn, "detected_level": LevelNameError}, labels)
+
+	LevelLabel{}.Apply(labels, slog.LevelInfo)
+	require.Len(t, labels, 2)
+}
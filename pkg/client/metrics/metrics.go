@@ -0,0 +1,136 @@
+// Package metrics provides instrumentation for the client pipeline, recording how many entries and bytes are sent,
+// how long pushes take, how they fail, and how retries behave.
+//
+// Events are reported to a [Recorder], a small interface that can be implemented on top of any metrics library. The
+// [Registry] is a dependency-free implementation that renders the metrics in the Prometheus text exposition format
+// through an [http.Handler].
+//
+// # Wiring
+//
+// The [Client] records each individual push, so it should wrap the client that actually talks to Loki. Retries, drops
+// and the number of pushes waiting to complete are reported through a [retry.Observer] created by [NewRetryObserver]:
+//
+//	registry := metrics.NewRegistry()
+//	lokiClient := metrics.NewClient(client.NewLokiClient(url), registry)
+//	retryClient := retry.NewRetryClient(lokiClient).WithObserver(metrics.NewRetryObserver(registry))
+//	http.Handle("/metrics", registry)
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/client/retry"
+)
+
+// NoStatusCode is the status code reported to [Recorder.PushFailed] when a push failed without a response from Loki,
+// e.g. because of a network error or a canceled context.
+const NoStatusCode = "none"
+
+// Recorder is an interface that receives the events recorded by the instrumentation in this package. Implementations
+// should be fast and safe to call concurrently.
+type Recorder interface {
+	// EntrySent records an entry that was successfully pushed. The size is the number of bytes in the line and the
+	// structured metadata, before encoding and compression.
+	EntrySent(size int)
+	// PushDuration records the duration of a single push, whether it succeeded or not.
+	PushDuration(duration time.Duration)
+	// PushFailed records a failed push with the HTTP status code of the response or [NoStatusCode].
+	PushFailed(statusCode string)
+	// Retried records that a failed push is being retried.
+	Retried()
+	// Dropped records an entry that was given up on after all retries.
+	Dropped()
+	// QueueDepthChanged records a change to the number of pushes that have not completed yet.
+	QueueDepthChanged(delta int)
+}
+
+// Client is a client that records metrics about every push to the inner client. It implements the [client.Client]
+// interface and is safe to use concurrently as long as the inner client and the recorder are.
+type Client struct {
+	inner    client.Client
+	recorder Recorder
+}
+
+// NewClient creates a new Client wrapping the given client and recording metrics to the given recorder.
+func NewClient(inner client.Client, recorder Recorder) *Client {
+	return &Client{
+		inner:    inner,
+		recorder: recorder,
+	}
+}
+
+// Assert that Client implements the [client.Client] interface.
+var _ client.Client = (*Client)(nil)
+
+// Push implements the [client.Client] interface. It pushes the entry using the inner client, recording the duration
+// and either the size of the entry or the failure.
+func (metricsClient *Client) Push(ctx context.Context, entry client.Entry) error {
+	start := time.Now()
+	err := metricsClient.inner.Push(ctx, entry)
+
+	metricsClient.recorder.PushDuration(time.Since(start))
+
+	if err != nil {
+		metricsClient.recorder.PushFailed(statusCode(err))
+
+		return err
+	}
+
+	metricsClient.recorder.EntrySent(entrySize(entry))
+
+	return nil
+}
+
+// retryObserver adapts a [Recorder] to the [retry.Observer] interface.
+type retryObserver struct {
+	recorder Recorder
+}
+
+// NewRetryObserver returns a [retry.Observer] that records retries, dropped entries, and the queue depth of a
+// [retry.Client] to the given recorder.
+//
+//nolint:ireturn // The observer is only useful as a retry.Observer.
+func NewRetryObserver(recorder Recorder) retry.Observer {
+	return &retryObserver{recorder: recorder}
+}
+
+func (observer *retryObserver) Queued() {
+	observer.recorder.QueueDepthChanged(1)
+}
+
+func (observer *retryObserver) Retried() {
+	observer.recorder.Retried()
+}
+
+func (observer *retryObserver) Done(err error) {
+	observer.recorder.QueueDepthChanged(-1)
+
+	if err != nil {
+		observer.recorder.Dropped()
+	}
+}
+
+// statusCode returns the status code of the error if it is a [client.PushStatusError] and [NoStatusCode] otherwise.
+func statusCode(err error) string {
+	var statusErr *client.PushStatusError
+	if errors.As(err, &statusErr) {
+		return strconv.Itoa(statusErr.StatusCode)
+	}
+
+	return NoStatusCode
+}
+
+// entrySize returns the number of bytes in the line and structured metadata of the entry.
+func entrySize(entry client.Entry) int {
+	size := len(entry.Line)
+
+	for key, value := range entry.StructuredMetadata {
+		size += len(key) + len(value)
+	}
+
+	return size
+}
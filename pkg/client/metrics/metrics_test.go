@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/client/retry"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+var testEntry = client.Entry{
+	Timestamp:          time.Now(),
+	Labels:             client.LabelMap{"foo": "bar"},
+	Line:               "test message",
+	StructuredMetadata: map[string]string{"key": "value"},
+}
+
+// errorClient is a client that always fails with the same error.
+type errorClient struct {
+	err error
+}
+
+func (errClient errorClient) Push(context.Context, client.Entry) error {
+	return errClient.err
+}
+
+// renderRegistry returns the metrics of the registry as served over HTTP.
+func renderRegistry(t *testing.T, registry *Registry) string {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, ContentType, recorder.Header().Get("Content-Type"))
+
+	return recorder.Body.String()
+}
+
+func TestClient_Push(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.FailNext(1)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	registry := NewRegistry()
+	metricsClient := NewClient(client.NewLokiClient(httpServer.URL+client.PushPath), registry)
+
+	require.Error(t, metricsClient.Push(t.Context(), testEntry))
+	require.NoError(t, metricsClient.Push(t.Context(), testEntry))
+	require.Error(t, NewClient(errorClient{errors.New("test")}, registry).Push(t.Context(), testEntry))
+
+	output := renderRegistry(t, registry)
+	require.Contains(t, output, "loki_logger_entries_sent_total 1\n")
+	require.Contains(t, output, "loki_logger_sent_entry_bytes_total 20\n")
+	require.Contains(t, output, "loki_logger_push_failures_total{code=\"500\"} 1\n")
+	require.Contains(t, output, "loki_logger_push_failures_total{code=\"none\"} 1\n")
+	require.Contains(t, output, "loki_logger_push_duration_seconds_bucket{le=\"+Inf\"} 3\n")
+	require.Contains(t, output, "loki_logger_push_duration_seconds_count 3\n")
+}
+
+func TestRetryObserver(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		sendError int
+		backoff   *retry.ExponentialBackoff
+		expected  []string
+	}{
+		{
+			name:      "retried",
+			sendError: 2,
+			backoff:   &retry.ExponentialBackoff{Delay: time.Millisecond},
+			expected: []string{
+				"loki_logger_entries_sent_total 1\n",
+				"loki_logger_retries_total 2\n",
+				"loki_logger_dropped_entries_total 0\n",
+				"loki_logger_queue_depth 0\n",
+				"loki_logger_push_failures_total{code=\"500\"} 2\n",
+			},
+		},
+		{
+			name:      "dropped",
+			sendError: 10,
+			backoff:   &retry.ExponentialBackoff{Delay: time.Millisecond, Max: 2 * time.Millisecond},
+			expected: []string{
+				"loki_logger_entries_sent_total 0\n",
+				"loki_logger_retries_total 2\n",
+				"loki_logger_dropped_entries_total 1\n",
+				"loki_logger_queue_depth 0\n",
+				"loki_logger_push_failures_total{code=\"500\"} 3\n",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			fakeServer.FailNext(testCase.sendError)
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			registry := NewRegistry()
+			lokiClient := NewClient(client.NewLokiClient(httpServer.URL+client.PushPath), registry)
+			retryClient := retry.NewRetryClient(lokiClient).
+				WithBackoff(testCase.backoff).
+				WithObserver(NewRetryObserver(registry))
+
+			<-retryClient.PushWithHandle(t.Context(), testEntry)
+
+			output := renderRegistry(t, registry)
+			for _, expected := range testCase.expected {
+				require.Contains(t, output, expected)
+			}
+		})
+	}
+}
+
+func TestRegistry_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry(1, 0.1)
+	registry.PushDuration(50 * time.Millisecond)
+	registry.PushDuration(100 * time.Millisecond)
+	registry.PushDuration(2 * time.Second)
+	registry.QueueDepthChanged(3)
+
+	var builder strings.Builder
+
+	written, err := registry.WriteTo(&builder)
+	require.NoError(t, err)
+	require.Equal(t, int64(builder.Len()), written)
+
+	output := builder.String()
+	require.Contains(t, output, "# TYPE loki_logger_push_duration_seconds histogram\n")
+	require.Contains(t, output, "loki_logger_push_duration_seconds_bucket{le=\"0.1\"} 2\n")
+	require.Contains(t, output, "loki_logger_push_duration_seconds_bucket{le=\"1\"} 2\n")
+	require.Contains(t, output, "loki_logger_push_duration_seconds_bucket{le=\"+Inf\"} 3\n")
+	require.Contains(t, output, "loki_logger_push_duration_seconds_sum 2.15\n")
+	require.Contains(t, output, "loki_logger_queue_depth 3\n")
+}
@@ -0,0 +1,196 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ContentType is the value of the Content-Type header for the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the default upper bounds, in seconds, of the push duration histogram.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry is a [Recorder] that keeps the metrics in memory and renders them in the Prometheus text exposition format.
+// It implements [http.Handler] so it can be served directly as a metrics endpoint. It is safe to use concurrently.
+//
+// The following metrics are exposed:
+//
+//   - loki_logger_entries_sent_total: counter of entries successfully pushed
+//   - loki_logger_sent_entry_bytes_total: counter of bytes in the lines and structured metadata of the sent entries,
+//     before encoding and compression, so it does not reflect the size of the requests
+//   - loki_logger_push_failures_total: counter of failed pushes, labeled with the status code
+//   - loki_logger_retries_total: counter of retried pushes
+//   - loki_logger_dropped_entries_total: counter of entries dropped after exhausting all retries
+//   - loki_logger_queue_depth: gauge of pushes that have not completed yet
+//   - loki_logger_push_duration_seconds: histogram of push durations
+type Registry struct {
+	entriesSent    atomic.Uint64
+	entryBytesSent atomic.Uint64
+	retries        atomic.Uint64
+	dropped        atomic.Uint64
+	queueDepth     atomic.Int64
+
+	lock *sync.Mutex
+	// failures is the count of failed pushes by status code.
+	failures map[string]uint64
+	// buckets are the upper bounds of the histogram and counts the number of observations in each bucket. The
+	// counts are not cumulative.
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// Assert that Registry implements the [Recorder] and [http.Handler] interfaces.
+var (
+	_ Recorder     = (*Registry)(nil)
+	_ http.Handler = (*Registry)(nil)
+)
+
+// NewRegistry creates a new Registry using the given histogram buckets, or [DefaultBuckets] if none are provided. The
+// buckets are the upper bounds of the push duration histogram in seconds.
+func NewRegistry(buckets ...float64) *Registry {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	return &Registry{
+		lock:     &sync.Mutex{},
+		failures: make(map[string]uint64),
+		buckets:  buckets,
+		counts:   make([]uint64, len(buckets)+1),
+	}
+}
+
+// EntrySent implements the [Recorder] interface.
+func (registry *Registry) EntrySent(size int) {
+	registry.entriesSent.Add(1)
+	registry.entryBytesSent.Add(uint64(max(size, 0)))
+}
+
+// PushDuration implements the [Recorder] interface.
+func (registry *Registry) PushDuration(duration time.Duration) {
+	seconds := duration.Seconds()
+	bucket, _ := slices.BinarySearch(registry.buckets, seconds)
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	registry.counts[bucket]++
+	registry.sum += seconds
+	registry.count++
+}
+
+// PushFailed implements the [Recorder] interface.
+func (registry *Registry) PushFailed(statusCode string) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	registry.failures[statusCode]++
+}
+
+// Retried implements the [Recorder] interface.
+func (registry *Registry) Retried() {
+	registry.retries.Add(1)
+}
+
+// Dropped implements the [Recorder] interface.
+func (registry *Registry) Dropped() {
+	registry.dropped.Add(1)
+}
+
+// QueueDepthChanged implements the [Recorder] interface.
+func (registry *Registry) QueueDepthChanged(delta int) {
+	registry.queueDepth.Add(int64(delta))
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (registry *Registry) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	writer.Header().Set("Content-Type", ContentType)
+	_, _ = registry.WriteTo(writer)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format to the writer. It implements the [io.WriterTo]
+// interface.
+func (registry *Registry) WriteTo(writer io.Writer) (int64, error) {
+	counting := &countingWriter{writer: writer}
+	buffered := bufio.NewWriter(counting)
+
+	writeMetric(buffered, "loki_logger_entries_sent_total", "counter", "Total number of entries pushed to Loki.")
+	fmt.Fprintf(buffered, "loki_logger_entries_sent_total %d\n", registry.entriesSent.Load())
+	writeMetric(buffered, "loki_logger_sent_entry_bytes_total", "counter",
+		"Total number of bytes in the lines and structured metadata of entries pushed to Loki, before encoding.")
+	fmt.Fprintf(buffered, "loki_logger_sent_entry_bytes_total %d\n", registry.entryBytesSent.Load())
+	writeMetric(buffered, "loki_logger_retries_total", "counter", "Total number of retried pushes.")
+	fmt.Fprintf(buffered, "loki_logger_retries_total %d\n", registry.retries.Load())
+	writeMetric(buffered, "loki_logger_dropped_entries_total", "counter",
+		"Total number of entries dropped after exhausting all retries.")
+	fmt.Fprintf(buffered, "loki_logger_dropped_entries_total %d\n", registry.dropped.Load())
+	writeMetric(buffered, "loki_logger_queue_depth", "gauge", "Number of pushes that have not completed yet.")
+	fmt.Fprintf(buffered, "loki_logger_queue_depth %d\n", registry.queueDepth.Load())
+
+	registry.lock.Lock()
+	registry.writeLocked(buffered)
+	registry.lock.Unlock()
+
+	err := buffered.Flush()
+
+	return counting.written, err
+}
+
+// writeLocked writes the metrics protected by the lock. The lock must be held by the caller.
+func (registry *Registry) writeLocked(writer io.Writer) {
+	writeMetric(writer, "loki_logger_push_failures_total", "counter", "Total number of failed pushes by status code.")
+
+	for _, code := range slices.Sorted(maps.Keys(registry.failures)) {
+		fmt.Fprintf(writer, "loki_logger_push_failures_total{code=%q} %d\n", code, registry.failures[code])
+	}
+
+	writeMetric(writer, "loki_logger_push_duration_seconds", "histogram", "Duration of pushes to Loki in seconds.")
+
+	cumulative := uint64(0)
+
+	for i, bound := range registry.buckets {
+		cumulative += registry.counts[i]
+		fmt.Fprintf(writer, "loki_logger_push_duration_seconds_bucket{le=%q} %d\n", formatFloat(bound), cumulative)
+	}
+
+	fmt.Fprintf(writer, "loki_logger_push_duration_seconds_bucket{le=\"+Inf\"} %d\n", registry.count)
+	fmt.Fprintf(writer, "loki_logger_push_duration_seconds_sum %s\n", formatFloat(registry.sum))
+	fmt.Fprintf(writer, "loki_logger_push_duration_seconds_count %d\n", registry.count)
+}
+
+// writeMetric writes the HELP and TYPE lines for a metric.
+func writeMetric(writer io.Writer, name, metricType, help string) {
+	fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// formatFloat formats a float in the shortest representation that round-trips, as expected by Prometheus.
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// countingWriter is an [io.Writer] that counts the bytes written to the underlying writer.
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+func (writer *countingWriter) Write(data []byte) (int, error) {
+	count, err := writer.writer.Write(data)
+	writer.written += int64(count)
+
+	return count, err
+}
@@ -0,0 +1,310 @@
+// Package otlp provides a client that sends log entries to an OTLP/HTTP logs endpoint instead of the Loki push API.
+// Loki 3 accepts OTLP logs natively at [PushPath], so this allows the slog, logr and log adapters to target OTLP
+// without any changes.
+//
+// # Conversion
+//
+// Each [client.Entry] is converted to a single OTLP log record. The stream labels become resource attributes, except
+// for the level label, which becomes the severity of the record. The structured metadata becomes the attributes of the
+// log record and the line becomes its body. The level label is [LevelKey] unless changed using [Client.WithLevelKey],
+// which should match the key of the [client.LevelLabel] used by the adapter.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/internal/labels"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// PushPath is the path to the OTLP logs endpoint of Loki. Like [client.PushPath], it is not appended to the URL
+// automatically.
+const PushPath = "/otlp/v1/logs"
+
+// LevelKey is the default stream label that is converted to the severity of a log record, see [Client.WithLevelKey].
+const LevelKey = client.DefaultLevelKey
+
+// ScopeName is the name of the instrumentation scope of all log records sent by the [Client].
+const ScopeName = "github.com/tslnc04/loki-logger"
+
+const (
+	// contentTypeProtobuf is the value of the Content-Type header for binary protobuf requests.
+	contentTypeProtobuf = "application/x-protobuf"
+	// contentTypeJSON is the value of the Content-Type header for JSON requests.
+	contentTypeJSON = "application/json"
+	// userAgent is the value of the User-Agent header for requests. It is the same as for the Loki client.
+	userAgent = "loki-logger/0.0"
+)
+
+// Encoding is the encoding of the OTLP requests sent by the [Client].
+type Encoding int
+
+const (
+	// EncodingProtobuf encodes requests as binary protobuf. It is the default.
+	EncodingProtobuf Encoding = iota
+	// EncodingJSON encodes requests as JSON following the OTLP/HTTP JSON mapping.
+	EncodingJSON
+)
+
+// Client is a client for pushing log entries to an OTLP/HTTP logs endpoint. It implements the [client.Client]
+// interface and is safe to use concurrently.
+type Client struct {
+	url         string
+	client      *http.Client
+	encoding    Encoding
+	compression client.Compression
+	timeout     time.Duration
+	levelKey    string
+}
+
+// NewClient creates a new Client with the given URL, which should usually end with [PushPath]. It defaults to
+// [EncodingProtobuf]. Like [client.NewLokiClient], it uses an HTTP client with the transport returned by
+// [client.NewTransport] and applies [client.DefaultPushTimeout] to pushes whose context has no deadline.
+func NewClient(url string) *Client {
+	return &Client{
+		url:      url,
+		client:   &http.Client{Transport: client.NewTransport()},
+		timeout:  client.DefaultPushTimeout,
+		levelKey: LevelKey,
+	}
+}
+
+// WithHTTPClient sets the HTTP client to use for the Client. It is safe to call concurrently from multiple goroutines
+// as it returns a new Client struct.
+func (otlpClient *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	return &Client{
+		url:         otlpClient.url,
+		client:      httpClient,
+		encoding:    otlpClient.encoding,
+		compression: otlpClient.compression,
+		timeout:     otlpClient.timeout,
+		levelKey:    otlpClient.levelKey,
+	}
+}
+
+// WithEncoding sets the encoding of the requests. It is safe to call concurrently from multiple goroutines as it
+// returns a new Client struct.
+func (otlpClient *Client) WithEncoding(encoding Encoding) *Client {
+	return &Client{
+		url:         otlpClient.url,
+		client:      otlpClient.client,
+		encoding:    encoding,
+		compression: otlpClient.compression,
+		timeout:     otlpClient.timeout,
+		levelKey:    otlpClient.levelKey,
+	}
+}
+
+// WithCompression sets the Content-Encoding of the requests, see [client.Compression]. Unlike for the Loki push API,
+// the body is not compressed using Snappy, so [client.CompressionNone] sends it uncompressed. It is safe to call
+// concurrently from multiple goroutines as it returns a new Client struct.
+func (otlpClient *Client) WithCompression(compression client.Compression) *Client {
+	return &Client{
+		url:         otlpClient.url,
+		client:      otlpClient.client,
+		encoding:    otlpClient.encoding,
+		compression: compression,
+		timeout:     otlpClient.timeout,
+		levelKey:    otlpClient.levelKey,
+	}
+}
+
+// WithTimeout sets the timeout of pushes whose context has no deadline. It defaults to [client.DefaultPushTimeout],
+// and a timeout of zero disables it. A deadline of the context always takes precedence. It is safe to call
+// concurrently from multiple goroutines as it returns a new Client struct.
+func (otlpClient *Client) WithTimeout(timeout time.Duration) *Client {
+	return &Client{
+		url:         otlpClient.url,
+		client:      otlpClient.client,
+		encoding:    otlpClient.encoding,
+		compression: otlpClient.compression,
+		timeout:     timeout,
+		levelKey:    otlpClient.levelKey,
+	}
+}
+
+// WithLevelKey sets the stream label that is converted to the severity of the log records. It defaults to [LevelKey]
+// and should be changed together with the key of the [client.LevelLabel] of the adapter. An empty key keeps all labels
+// as resource attributes. It is safe to call concurrently from multiple goroutines as it returns a new Client struct.
+func (otlpClient *Client) WithLevelKey(levelKey string) *Client {
+	return &Client{
+		url:         otlpClient.url,
+		client:      otlpClient.client,
+		encoding:    otlpClient.encoding,
+		compression: otlpClient.compression,
+		timeout:     otlpClient.timeout,
+		levelKey:    levelKey,
+	}
+}
+
+// Assert that Client implements the [client.Client] interface.
+var _ client.Client = (*Client)(nil)
+
+// Push implements the [client.Client] interface. It converts the entry to an OTLP request and sends it. Failed
+// requests result in a [client.PushStatusError], the same as for [client.LokiClient]. Labels and structured metadata
+// carried by the context are added to the entry, see [client.MergeContext]. If the context has no deadline, the timeout
+// set using [Client.WithTimeout] is applied.
+func (otlpClient *Client) Push(ctx context.Context, entry client.Entry) error {
+	if _, ok := ctx.Deadline(); !ok && otlpClient.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, otlpClient.timeout)
+		defer cancel()
+	}
+
+	logsData, err := AsLogsData(client.MergeContext(ctx, entry), otlpClient.levelKey)
+	if err != nil {
+		return err
+	}
+
+	buf, contentType, err := otlpClient.encode(logsData)
+	if err != nil {
+		return err
+	}
+
+	if otlpClient.compression != client.CompressionNone {
+		buf, err = otlpClient.compression.Compress(nil, buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, otlpClient.url, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", userAgent)
+
+	if contentEncoding := otlpClient.compression.ContentEncoding(); contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+
+	resp, err := otlpClient.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return errors.Join(client.NewPushStatusError(resp.StatusCode, resp.Status, nil),
+				fmt.Errorf("failed to read response body: %w", err))
+		}
+
+		return client.NewPushStatusError(resp.StatusCode, resp.Status, body)
+	}
+
+	return nil
+}
+
+// encode serializes the request using the encoding of the client, returning the body and its content type.
+func (otlpClient *Client) encode(logsData *logsv1.LogsData) ([]byte, string, error) {
+	if otlpClient.encoding == EncodingJSON {
+		// The OTLP JSON mapping requires enums to be encoded as integers.
+		buf, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(logsData)
+
+		return buf, contentTypeJSON, err
+	}
+
+	buf, err := proto.Marshal(logsData)
+
+	return buf, contentTypeProtobuf, err
+}
+
+// AsLogsData converts the Entry to OTLP logs containing a single log record, with the stream label levelKey converted
+// to its severity. The result has the same wire format as an OTLP ExportLogsServiceRequest, both in protobuf and JSON.
+// It returns an error if the labels of the entry cannot be parsed.
+func AsLogsData(entry client.Entry, levelKey string) (*logsv1.LogsData, error) {
+	streamLabels := map[string]string{}
+
+	if entry.Labels != nil {
+		var err error
+
+		streamLabels, err = labels.Parse(string(entry.Labels.Label()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	level, hasLevel := streamLabels[levelKey]
+	delete(streamLabels, levelKey)
+
+	record := &logsv1.LogRecord{
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		Body:                 stringValue(entry.Line),
+		Attributes:           keyValues(entry.StructuredMetadata),
+	}
+
+	if !entry.Timestamp.IsZero() {
+		record.TimeUnixNano = uint64(entry.Timestamp.UnixNano())
+	}
+
+	if hasLevel {
+		record.SeverityText = level
+		record.SeverityNumber = severityNumber(level)
+	}
+
+	return &logsv1.LogsData{
+		ResourceLogs: []*logsv1.ResourceLogs{{
+			Resource: &resourcev1.Resource{Attributes: keyValues(streamLabels)},
+			ScopeLogs: []*logsv1.ScopeLogs{{
+				Scope:      &commonv1.InstrumentationScope{Name: ScopeName},
+				LogRecords: []*logsv1.LogRecord{record},
+			}},
+		}},
+	}, nil
+}
+
+// severityNumber converts the value of the level label to an OTLP severity number. Levels are parsed using
+// [client.ParseLevel], so both the names from [client.DefaultLevelMapper] and [slog.Level] strings are understood.
+// Unknown levels result in an unspecified severity.
+func severityNumber(level string) logsv1.SeverityNumber {
+	slogLevel, err := client.ParseLevel(level)
+	if err != nil {
+		return logsv1.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+	}
+
+	// The slog levels are spaced the same as the OTLP severity ranges, with slog.LevelInfo corresponding to
+	// SEVERITY_NUMBER_INFO.
+	severity := int(slogLevel) + int(logsv1.SeverityNumber_SEVERITY_NUMBER_INFO)
+	severity = min(max(severity, int(logsv1.SeverityNumber_SEVERITY_NUMBER_TRACE)),
+		int(logsv1.SeverityNumber_SEVERITY_NUMBER_FATAL4))
+
+	return logsv1.SeverityNumber(severity)
+}
+
+// keyValues converts a map to OTLP attributes with string values, sorted by key.
+func keyValues(values map[string]string) []*commonv1.KeyValue {
+	if len(values) == 0 {
+		return nil
+	}
+
+	keyValues := make([]*commonv1.KeyValue, 0, len(values))
+
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		keyValues = append(keyValues, &commonv1.KeyValue{Key: key, Value: stringValue(values[key])})
+	}
+
+	return keyValues
+}
+
+// stringValue wraps the string in an OTLP AnyValue.
+func stringValue(value string) *commonv1.AnyValue {
+	return &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: value}}
+}
@@ -0,0 +1,266 @@
+package otlp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+	lokislog "github.com/tslnc04/loki-logger/pkg/slog"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+var testTimestamp = time.Date(2025, 5, 27, 0, 0, 0, 0, time.UTC)
+
+func TestNewClient(t *testing.T) {
+	t.Parallel()
+
+	const url = "http://localhost:3100" + PushPath
+
+	otlpClient := NewClient(url)
+	require.Equal(t, url, otlpClient.url)
+	require.NotNil(t, otlpClient.client)
+	require.Equal(t, EncodingProtobuf, otlpClient.encoding)
+	require.Equal(t, client.DefaultPushTimeout, otlpClient.timeout)
+	require.Equal(t, LevelKey, otlpClient.levelKey)
+
+	withJSON := otlpClient.WithEncoding(EncodingJSON)
+	require.Equal(t, EncodingJSON, withJSON.encoding)
+
+	withHTTP := withJSON.WithHTTPClient(&http.Client{Timeout: time.Second})
+	require.Equal(t, &http.Client{Timeout: time.Second}, withHTTP.client)
+	require.Equal(t, EncodingJSON, withHTTP.encoding)
+
+	withLevelKey := withHTTP.WithLevelKey("detected_level")
+	require.Equal(t, "detected_level", withLevelKey.levelKey)
+	require.Equal(t, EncodingJSON, withLevelKey.encoding)
+	require.Equal(t, "detected_level", withLevelKey.WithTimeout(time.Second).levelKey)
+
+	// The original client should not be modified.
+	require.Equal(t, EncodingProtobuf, otlpClient.encoding)
+	require.Equal(t, LevelKey, withHTTP.levelKey)
+	require.Zero(t, otlpClient.client.Timeout)
+}
+
+func TestClient_Push(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		encoding    Encoding
+		compression client.Compression
+		sendError   int
+	}{
+		{
+			name:     "protobuf",
+			encoding: EncodingProtobuf,
+		},
+		{
+			name:     "json",
+			encoding: EncodingJSON,
+		},
+		{
+			name:        "json-gzip",
+			encoding:    EncodingJSON,
+			compression: client.CompressionGzip,
+		},
+		{
+			name:        "protobuf-zstd",
+			encoding:    EncodingProtobuf,
+			compression: client.CompressionZstd,
+		},
+		{
+			name:      "error",
+			encoding:  EncodingProtobuf,
+			sendError: 1,
+		},
+	}
+
+	entry := client.Entry{
+		Timestamp:          testTimestamp,
+		Labels:             client.LabelMap{"service_name": "test", LevelKey: "INFO"},
+		Line:               "test message",
+		StructuredMetadata: map[string]string{"key": "value"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			fakeServer.FailNext(testCase.sendError)
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			otlpClient := NewClient(httpServer.URL + PushPath).WithEncoding(testCase.encoding).
+				WithCompression(testCase.compression)
+			err := otlpClient.Push(t.Context(), entry)
+
+			streams := fakeServer.Streams()
+
+			if testCase.sendError > 0 {
+				require.ErrorIs(t, err, &client.PushStatusError{})
+				require.Empty(t, streams)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, streams, 1)
+			client.AssertStreamMatchesEntry(t, entry, streams[0])
+		})
+	}
+}
+
+func TestClient_WithTimeout(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.SetLatency(100 * time.Millisecond)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	entry := client.Entry{
+		Timestamp: testTimestamp,
+		Labels:    client.LabelMap{"app": "test"},
+		Line:      "test message",
+	}
+
+	otlpClient := NewClient(httpServer.URL + PushPath).WithTimeout(10 * time.Millisecond)
+	require.ErrorIs(t, otlpClient.Push(t.Context(), entry), context.DeadlineExceeded)
+
+	// A deadline of the context takes precedence.
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+
+	require.NoError(t, otlpClient.Push(ctx, entry))
+}
+
+func TestClient_PushInvalidLabels(t *testing.T) {
+	t.Parallel()
+
+	otlpClient := NewClient("http://localhost" + PushPath)
+	err := otlpClient.Push(t.Context(), client.Entry{Labels: client.LabelString("invalid")})
+
+	require.Error(t, err)
+}
+
+func TestClient_SlogAdapter(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	logger := lokislog.NewLogger(NewClient(httpServer.URL+PushPath), nil).With("service_name", "test")
+	logger.WarnContext(t.Context(), "test message", "key", "value")
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 1)
+	client.AssertStreamMatchesEntry(t, client.Entry{
+		Labels:             client.LabelMap{"service_name": "test", LevelKey: client.LevelNameWarn},
+		Line:               "test message",
+		StructuredMetadata: map[string]string{"key": "value"},
+	}, streams[0])
+}
+
+func TestAsLogsData(t *testing.T) {
+	t.Parallel()
+
+	logsData, err := AsLogsData(client.Entry{
+		Timestamp:          testTimestamp,
+		Labels:             client.LabelMap{"b": "2", "a": "1", LevelKey: "error"},
+		Line:               "test message",
+		StructuredMetadata: map[string]string{"key": "value"},
+	}, LevelKey)
+	require.NoError(t, err)
+	require.Len(t, logsData.GetResourceLogs(), 1)
+
+	resourceLogs := logsData.GetResourceLogs()[0]
+	attributes := resourceLogs.GetResource().GetAttributes()
+	require.Len(t, attributes, 2)
+	require.Equal(t, "a", attributes[0].GetKey())
+	require.Equal(t, "b", attributes[1].GetKey())
+
+	require.Len(t, resourceLogs.GetScopeLogs(), 1)
+	require.Equal(t, ScopeName, resourceLogs.GetScopeLogs()[0].GetScope().GetName())
+
+	record := resourceLogs.GetScopeLogs()[0].GetLogRecords()[0]
+	require.Equal(t, uint64(testTimestamp.UnixNano()), record.GetTimeUnixNano())
+	require.NotZero(t, record.GetObservedTimeUnixNano())
+	require.Equal(t, "error", record.GetSeverityText())
+	require.Equal(t, logsv1.SeverityNumber_SEVERITY_NUMBER_ERROR, record.GetSeverityNumber())
+	require.Equal(t, "test message", record.GetBody().GetStringValue())
+	require.Equal(t, "value", record.GetAttributes()[0].GetValue().GetStringValue())
+}
+
+func TestAsLogsData_LevelKey(t *testing.T) {
+	t.Parallel()
+
+	entry := client.Entry{Labels: client.LabelMap{"app": "test", "detected_level": "warn"}}
+
+	logsData, err := AsLogsData(entry, "detected_level")
+	require.NoError(t, err)
+
+	resourceLogs := logsData.GetResourceLogs()[0]
+	require.Len(t, resourceLogs.GetResource().GetAttributes(), 1)
+	require.Equal(t, "app", resourceLogs.GetResource().GetAttributes()[0].GetKey())
+
+	record := resourceLogs.GetScopeLogs()[0].GetLogRecords()[0]
+	require.Equal(t, "warn", record.GetSeverityText())
+	require.Equal(t, logsv1.SeverityNumber_SEVERITY_NUMBER_WARN, record.GetSeverityNumber())
+
+	// Without a level key, all labels are kept as resource attributes.
+	logsData, err = AsLogsData(entry, "")
+	require.NoError(t, err)
+	require.Len(t, logsData.GetResourceLogs()[0].GetResource().GetAttributes(), 2)
+	require.Empty(t, logsData.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0].GetSeverityText())
+}
+
+func TestAsLogsData_EmptyEntry(t *testing.T) {
+	t.Parallel()
+
+	logsData, err := AsLogsData(client.Entry{}, LevelKey)
+	require.NoError(t, err)
+
+	record := logsData.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0]
+	require.Zero(t, record.GetTimeUnixNano())
+	require.Empty(t, record.GetSeverityText())
+	require.Empty(t, logsData.GetResourceLogs()[0].GetResource().GetAttributes())
+}
+
+func TestSeverityNumber(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		level    string
+		expected logsv1.SeverityNumber
+	}{
+		{level: "DEBUG-8", expected: logsv1.SeverityNumber_SEVERITY_NUMBER_TRACE},
+		{level: "DEBUG", expected: logsv1.SeverityNumber_SEVERITY_NUMBER_DEBUG},
+		{level: "info", expected: logsv1.SeverityNumber_SEVERITY_NUMBER_INFO},
+		{level: "INFO+2", expected: logsv1.SeverityNumber_SEVERITY_NUMBER_INFO3},
+		{level: "WARN", expected: logsv1.SeverityNumber_SEVERITY_NUMBER_WARN},
+		{level: "ERROR", expected: logsv1.SeverityNumber_SEVERITY_NUMBER_ERROR},
+		{level: "ERROR+100", expected: logsv1.SeverityNumber_SEVERITY_NUMBER_FATAL4},
+		{level: "trace", expected: logsv1.SeverityNumber_SEVERITY_NUMBER_TRACE},
+		{level: "warning", expected: logsv1.SeverityNumber_SEVERITY_NUMBER_WARN},
+		{level: "critical", expected: logsv1.SeverityNumber_SEVERITY_NUMBER_FATAL},
+		{level: "unknown", expected: logsv1.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.level, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testCase.expected, severityNumber(testCase.level))
+		})
+	}
+}
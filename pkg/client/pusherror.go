@@ -0,0 +1,120 @@
+package client
+
+import (
+	"net/http"
+	"strings"
+)
+
+// PushErrorReason is the category of a failed push, parsed from the response of Loki. See [PushStatusError].
+type PushErrorReason string
+
+const (
+	// ReasonUnknown means the response did not match any known category, e.g. because the push failed with a server
+	// error.
+	ReasonUnknown PushErrorReason = "unknown"
+	// ReasonRateLimited means the ingestion rate limit of the tenant or the rate limit of a stream was exceeded.
+	ReasonRateLimited PushErrorReason = "rate_limited"
+	// ReasonTooOld means an entry was older than the maximum sample age of Loki.
+	ReasonTooOld PushErrorReason = "greater_than_max_sample_age"
+	// ReasonOutOfOrder means an entry was out of order or too far behind the newest entry of its stream.
+	ReasonOutOfOrder PushErrorReason = "out_of_order"
+	// ReasonLineTooLong means the line of an entry exceeded the maximum line size of Loki.
+	ReasonLineTooLong PushErrorReason = "line_too_long"
+	// ReasonInvalidLabels means the labels of a stream were invalid, e.g. because they could not be parsed, there were
+	// too many of them, or a name or value was too long.
+	ReasonInvalidLabels PushErrorReason = "invalid_labels"
+	// ReasonTenantLimit means a limit of the tenant other than the rate limit was exceeded, such as the maximum number of
+	// active streams.
+	ReasonTenantLimit PushErrorReason = "tenant_limit"
+)
+
+// reasonPatterns maps lowercase substrings of the error messages of Loki to their reason. The patterns are checked in
+// order, so more specific patterns must come first.
+var reasonPatterns = []struct {
+	pattern string
+	reason  PushErrorReason
+}{
+	{"maximum active stream limit exceeded", ReasonTenantLimit},
+	{"stream limit exceeded", ReasonTenantLimit},
+	{"rate limit exceeded", ReasonRateLimited},
+	{"timestamp too old", ReasonTooOld},
+	{"out of order", ReasonOutOfOrder},
+	{"too far behind", ReasonOutOfOrder},
+	{"max entry size", ReasonLineTooLong},
+	{"line too long", ReasonLineTooLong},
+	{"error parsing labels", ReasonInvalidLabels},
+	{"label name too long", ReasonInvalidLabels},
+	{"label value too long", ReasonInvalidLabels},
+	{"duplicate label name", ReasonInvalidLabels},
+	{"label names; limit", ReasonInvalidLabels},
+	{"at least one label pair is required", ReasonInvalidLabels},
+}
+
+// NewPushStatusError creates a new [PushStatusError] from a failed response, parsing the reason and the affected stream
+// from the body. The body may be nil if it could not be read.
+func NewPushStatusError(statusCode int, status string, body []byte) *PushStatusError {
+	reason, stream := parsePushError(statusCode, string(body))
+
+	return &PushStatusError{
+		StatusCode: statusCode,
+		Status:     status,
+		Body:       body,
+		Reason:     reason,
+		Stream:     stream,
+	}
+}
+
+// Retryable reports whether retrying the push may succeed. This is the case for rate limits and server errors, while
+// other client errors are caused by the entry itself and fail the same way every time.
+func (e *PushStatusError) Retryable() bool {
+	return e.Reason == ReasonRateLimited || e.StatusCode >= http.StatusInternalServerError
+}
+
+// parsePushError returns the reason of the first line of the body that matches a known error message of Loki, along
+// with the first stream labels mentioned from that line on, since Loki sometimes names the stream on a following line.
+// If no line matches, responses with status 429 are considered rate limited and all others unknown.
+func parsePushError(statusCode int, body string) (PushErrorReason, LabelString) {
+	offset := 0
+
+	for line := range strings.SplitSeq(body, "\n") {
+		lowerLine := strings.ToLower(line)
+
+		for _, pattern := range reasonPatterns {
+			if strings.Contains(lowerLine, pattern.pattern) {
+				return pattern.reason, findStream(body[offset:])
+			}
+		}
+
+		offset += len(line) + 1
+	}
+
+	if statusCode == http.StatusTooManyRequests {
+		return ReasonRateLimited, findStream(body)
+	}
+
+	return ReasonUnknown, ""
+}
+
+// findStream returns the first label string in the message, i.e. the first text enclosed in braces, skipping over
+// quoted label values. It returns an empty string if there is none.
+func findStream(message string) LabelString {
+	start := strings.IndexByte(message, '{')
+	if start < 0 {
+		return ""
+	}
+
+	inQuotes := false
+
+	for i := start + 1; i < len(message); i++ {
+		switch {
+		case inQuotes && message[i] == '\\':
+			i++
+		case message[i] == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && message[i] == '}':
+			return LabelString(message[start : i+1])
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:funlen // Most of the function is test cases, no need to worry about length.
+func TestNewPushStatusError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		statusCode     int
+		body           string
+		expectedReason PushErrorReason
+		expectedStream LabelString
+		retryable      bool
+	}{
+		{
+			name:           "server-error",
+			statusCode:     http.StatusInternalServerError,
+			body:           "Internal Server Error",
+			expectedReason: ReasonUnknown,
+			retryable:      true,
+		},
+		{
+			name:       "rate-limited",
+			statusCode: http.StatusTooManyRequests,
+			body: "Ingestion rate limit exceeded for user fake (limit: 4194304 bytes/sec) while attempting to ingest " +
+				"'1000' lines totaling '5000000' bytes, reduce log volume or contact your Loki administrator to see " +
+				"if the limit can be increased",
+			expectedReason: ReasonRateLimited,
+			retryable:      true,
+		},
+		{
+			name:       "stream-rate-limited",
+			statusCode: http.StatusTooManyRequests,
+			body: `Per stream rate limit exceeded (limit: 3MB/sec) while attempting to ingest for stream ` +
+				`'{app="test"}' totaling 4MB, consider splitting a stream via additional labels`,
+			expectedReason: ReasonRateLimited,
+			expectedStream: `{app="test"}`,
+			retryable:      true,
+		},
+		{
+			name:           "bare-too-many-requests",
+			statusCode:     http.StatusTooManyRequests,
+			body:           "slow down",
+			expectedReason: ReasonRateLimited,
+			retryable:      true,
+		},
+		{
+			name:       "stream-limit",
+			statusCode: http.StatusTooManyRequests,
+			body: `Maximum active stream limit exceeded when trying to create stream {app="test"}, reduce the ` +
+				`number of active streams (reduce labels or reduce label values), or contact your Loki administrator`,
+			expectedReason: ReasonTenantLimit,
+			expectedStream: `{app="test"}`,
+		},
+		{
+			name:       "too-old",
+			statusCode: http.StatusBadRequest,
+			body: `entry for stream '{app="test"}' has timestamp too old: 2020-01-01T00:00:00Z, oldest acceptable ` +
+				`timestamp is: 2024-01-01T00:00:00Z`,
+			expectedReason: ReasonTooOld,
+			expectedStream: `{app="test"}`,
+		},
+		{
+			name:       "out-of-order",
+			statusCode: http.StatusBadRequest,
+			body: "entry with timestamp 2024-01-01 00:00:00 +0000 UTC ignored, reason: 'entry out of order',\n" +
+				`total ignored: 1 out of 1 for stream: {app="test", msg="}"}`,
+			expectedReason: ReasonOutOfOrder,
+			expectedStream: `{app="test", msg="}"}`,
+		},
+		{
+			name:       "line-too-long",
+			statusCode: http.StatusBadRequest,
+			body: `Max entry size '256' bytes exceeded for stream '{app="test"}' while adding an entry with ` +
+				`length '300' bytes`,
+			expectedReason: ReasonLineTooLong,
+			expectedStream: `{app="test"}`,
+		},
+		{
+			name:           "invalid-labels",
+			statusCode:     http.StatusBadRequest,
+			body:           `stream '{app="test"}' has label value too long: 'test'`,
+			expectedReason: ReasonInvalidLabels,
+			expectedStream: `{app="test"}`,
+		},
+		{
+			name:           "unknown-client-error",
+			statusCode:     http.StatusBadRequest,
+			body:           `something went wrong for {app="test"`,
+			expectedReason: ReasonUnknown,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := NewPushStatusError(testCase.statusCode, http.StatusText(testCase.statusCode), []byte(testCase.body))
+
+			require.Equal(t, testCase.statusCode, err.StatusCode)
+			require.Equal(t, []byte(testCase.body), err.Body)
+			require.Equal(t, testCase.expectedReason, err.Reason)
+			require.Equal(t, testCase.expectedStream, err.Stream)
+			require.Equal(t, testCase.retryable, err.Retryable())
+		})
+	}
+}
@@ -1,10 +1,15 @@
 // Package retry provides a thin wrapper around the [client.Client] interface that retries the push request with
 // exponential backoff if it fails.
+//
+// Only failures that may succeed when retried are retried, i.e. server errors and rate limits, as reported by
+// [client.PushStatusError.Retryable]. Entries rejected by Loki for other reasons, such as being too old or having
+// invalid labels, fail immediately.
 package retry
 
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/tslnc04/loki-logger/pkg/client"
@@ -79,20 +84,37 @@ func (b *ExponentialBackoff) Clone() Backoff {
 	}
 }
 
+// Observer is notified about the progress of pushes made through [Client]. It can be used to collect metrics, such as
+// with the metrics package. Since the methods are called synchronously from the goroutines doing the pushes,
+// implementations should be fast and safe to call concurrently.
+type Observer interface {
+	// Queued is called when a push is started, before the first attempt.
+	Queued()
+	// Retried is called before each retry of a push.
+	Retried()
+	// Done is called once a push has either succeeded or given up. The error is nil if the push succeeded.
+	Done(err error)
+}
+
 // Client is a client that retries the push request with exponential backoff if it fails. It implements the
 // [Client] interface. It is safe to call concurrently from multiple goroutines, although this may result in multiple
 // requests being in flight and retrying at the same time.
 type Client struct {
-	inner   client.Client
-	backoff Backoff
+	inner    client.Client
+	backoff  Backoff
+	observer Observer
+	// inflight tracks the pushes that have not completed yet. It is shared with Clients derived from this one, so that
+	// Flush waits for the pushes made through any of them.
+	inflight *tracker
 }
 
 // NewRetryClient creates a new RetryClient with the given client. It defaults to using the default values for
 // [ExponentialBackoff].
 func NewRetryClient(client client.Client) *Client {
 	return &Client{
-		inner:   client,
-		backoff: &ExponentialBackoff{},
+		inner:    client,
+		backoff:  &ExponentialBackoff{},
+		inflight: newTracker(),
 	}
 }
 
@@ -100,13 +122,29 @@ func NewRetryClient(client client.Client) *Client {
 // and will return a new RetryClient with the same inner client and the given backoff strategy.
 func (retryClient *Client) WithBackoff(backoff Backoff) *Client {
 	return &Client{
-		inner:   retryClient.inner,
-		backoff: backoff.Clone(),
+		inner:    retryClient.inner,
+		backoff:  backoff.Clone(),
+		observer: retryClient.observer,
+		inflight: retryClient.inflight,
+	}
+}
+
+// WithObserver sets the observer notified about the progress of pushes. It is safe to call concurrently from multiple
+// goroutines and will return a new RetryClient with the same inner client, backoff strategy, and the given observer.
+func (retryClient *Client) WithObserver(observer Observer) *Client {
+	return &Client{
+		inner:    retryClient.inner,
+		backoff:  retryClient.backoff.Clone(),
+		observer: observer,
+		inflight: retryClient.inflight,
 	}
 }
 
-// Assert that RetryClient implements the [client.Client] interface.
-var _ client.Client = (*Client)(nil)
+// Assert that RetryClient implements the [client.Client] and [client.Flusher] interfaces.
+var (
+	_ client.Client  = (*Client)(nil)
+	_ client.Flusher = (*Client)(nil)
+)
 
 // Push implements the [Client] interface. It retries the push request with exponential backoff if it fails.
 func (retryClient *Client) Push(ctx context.Context, entry client.Entry) error {
@@ -116,26 +154,25 @@ func (retryClient *Client) Push(ctx context.Context, entry client.Entry) error {
 }
 
 // PushWithHandle is similar to [Push] but returns a channel that will have a single error sent when the push exhausts
-// all retries. If the push succeeds before the retries are exhausted, the channel will be closed without sending an
-// error.
+// all retries or the context is done. If the push succeeds before the retries are exhausted, the channel will be
+// closed without sending an error.
 func (retryClient *Client) PushWithHandle(ctx context.Context, entry client.Entry) <-chan error {
 	errChan := make(chan error, 1)
 	clonedBackoff := retryClient.backoff.Clone()
 
+	if retryClient.observer != nil {
+		retryClient.observer.Queued()
+	}
+
+	id := retryClient.inflight.start()
+
 	go func() {
-		err := retryClient.inner.Push(ctx, entry)
-
-		for errors.Is(err, &client.PushStatusError{}) {
-			select {
-			case _, ok := <-clonedBackoff.Next():
-				if !ok {
-					return
-				}
-
-				err = retryClient.inner.Push(ctx, entry)
-			case <-ctx.Done():
-				return
-			}
+		defer retryClient.inflight.finish(id)
+
+		err := retryClient.pushWithRetries(ctx, entry, clonedBackoff)
+
+		if retryClient.observer != nil {
+			retryClient.observer.Done(err)
 		}
 
 		if err != nil {
@@ -147,3 +184,139 @@ func (retryClient *Client) PushWithHandle(ctx context.Context, entry client.Entr
 
 	return errChan
 }
+
+// Flush implements the [client.Flusher] interface. It waits until all pushes started before the call, through this
+// Client or any Client derived from it, have either succeeded or exhausted their retries, or until the context is
+// done, in which case the error of the context is returned. Pushes started during the call are not waited for, so
+// Flush returns even while other goroutines keep logging. If the inner client implements [client.Flusher], it is
+// flushed afterwards. Errors of the pushes themselves are only reported through the channels returned by
+// [Client.PushWithHandle].
+func (retryClient *Client) Flush(ctx context.Context) error {
+	if err := retryClient.inflight.wait(ctx, retryClient.inflight.started()); err != nil {
+		return err
+	}
+
+	if flusher, ok := retryClient.inner.(client.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+
+	return nil
+}
+
+// pushWithRetries pushes the entry to the inner client, retrying as long as the push fails with a retryable
+// [client.PushStatusError] and the backoff has not completed. It returns the error of the last attempt, or the error of
+// the context if it is done first.
+func (retryClient *Client) pushWithRetries(ctx context.Context, entry client.Entry, backoff Backoff) error {
+	err := retryClient.inner.Push(ctx, entry)
+
+	for retryable(err) {
+		select {
+		case _, ok := <-backoff.Next():
+			if !ok {
+				return err
+			}
+
+			if retryClient.observer != nil {
+				retryClient.observer.Retried()
+			}
+
+			err = retryClient.inner.Push(ctx, entry)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return err
+}
+
+// retryable reports whether the error is a [client.PushStatusError] that may succeed when retried, see
+// [client.PushStatusError.Retryable]. Errors that are caused by the entry itself, such as invalid labels, are not
+// retried.
+func retryable(err error) bool {
+	var statusErr *client.PushStatusError
+
+	return errors.As(err, &statusErr) && statusErr.Retryable()
+}
+
+// tracker tracks the pushes in flight, allowing to wait for the ones started before a given point in time. Unlike a
+// [sync.WaitGroup], waiting does not conflict with new pushes being started concurrently. It is safe to use
+// concurrently.
+type tracker struct {
+	lock sync.Mutex
+	// next is the ID of the next push to start. IDs increase monotonically.
+	next uint64
+	// pending are the IDs of the pushes that have not finished yet.
+	pending map[uint64]struct{}
+	// finished is closed and replaced whenever a push finishes, waking up any waiting callers.
+	finished chan struct{}
+}
+
+func newTracker() *tracker {
+	return &tracker{
+		pending:  make(map[uint64]struct{}),
+		finished: make(chan struct{}),
+	}
+}
+
+// start records a new push and returns its ID, which must be passed to finish once the push is done.
+func (tracker *tracker) start() uint64 {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	id := tracker.next
+	tracker.next++
+	tracker.pending[id] = struct{}{}
+
+	return id
+}
+
+// finish records that the push with the ID is done and wakes up any waiting callers.
+func (tracker *tracker) finish(id uint64) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	delete(tracker.pending, id)
+	close(tracker.finished)
+	tracker.finished = make(chan struct{})
+}
+
+// started returns the number of pushes started so far, which is the ID of the next push.
+func (tracker *tracker) started() uint64 {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	return tracker.next
+}
+
+// wait blocks until all pushes with an ID lower than before have finished or the context is done, in which case the
+// error of the context is returned. Pushes started later are not waited for.
+func (tracker *tracker) wait(ctx context.Context, before uint64) error {
+	for {
+		tracker.lock.Lock()
+		finished := tracker.finished
+		pending := tracker.pendingBefore(before)
+		tracker.lock.Unlock()
+
+		if !pending {
+			return nil
+		}
+
+		select {
+		case <-finished:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// pendingBefore reports whether any push with an ID lower than the given one is still pending. It must only be called
+// while holding the lock.
+func (tracker *tracker) pendingBefore(before uint64) bool {
+	for id := range tracker.pending {
+		if id < before {
+			return true
+		}
+	}
+
+	return false
+}
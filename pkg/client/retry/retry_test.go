@@ -1,12 +1,16 @@
 package retry
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/tslnc04/loki-logger/pkg/client"
-	"github.com/tslnc04/loki-logger/pkg/internal/fake"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
 )
 
 func TestExponentialBackoff_Clone(t *testing.T) {
@@ -141,7 +145,7 @@ func TestRetryClient_PushWithHandle(t *testing.T) {
 
 	testCases := []struct {
 		name      string
-		sendError uint
+		sendError int
 	}{
 		{
 			name:      "success",
@@ -158,7 +162,8 @@ func TestRetryClient_PushWithHandle(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			t.Parallel()
 
-			fakeServer := fake.NewServer(testCase.sendError)
+			fakeServer := lokitest.NewServer()
+			fakeServer.FailNext(testCase.sendError)
 			httpServer := fakeServer.Start()
 
 			defer httpServer.Close()
@@ -170,10 +175,267 @@ func TestRetryClient_PushWithHandle(t *testing.T) {
 			require.NoError(t, <-errChan)
 
 			streams := fakeServer.Streams()
-			defer fakeServer.Close()
 
 			require.Len(t, streams, 1, "Expected one push stream to be sent to the server")
 			client.AssertStreamMatchesEntry(t, testEntry, streams[0])
 		})
 	}
 }
+
+// TestRetryClient_PushWithHandleGivesUp checks that a push that gives up reports its error and closes the handle, so
+// that callers waiting on the handle do not block forever.
+func TestRetryClient_PushWithHandleGivesUp(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.FailNext(10)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+
+	// The backoff is exhausted after two retries.
+	retryClient := NewRetryClient(lokiClient).
+		WithBackoff(&ExponentialBackoff{Delay: time.Millisecond, Max: 2 * time.Millisecond})
+
+	handle := retryClient.PushWithHandle(t.Context(), client.Entry{})
+	require.ErrorIs(t, <-handle, &client.PushStatusError{})
+
+	_, ok := <-handle
+	require.False(t, ok, "Expected the handle to be closed")
+
+	// The context is done before the backoff completes.
+	retryClient = NewRetryClient(lokiClient).WithBackoff(&ExponentialBackoff{Delay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	handle = retryClient.PushWithHandle(ctx, client.Entry{})
+	require.ErrorIs(t, <-handle, context.DeadlineExceeded)
+
+	_, ok = <-handle
+	require.False(t, ok, "Expected the handle to be closed")
+}
+
+// countingObserver is an Observer that counts the notifications it receives.
+type countingObserver struct {
+	lock    sync.Mutex
+	queued  int
+	retried int
+	errs    []error
+}
+
+func (observer *countingObserver) Queued() {
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	observer.queued++
+}
+
+func (observer *countingObserver) Retried() {
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	observer.retried++
+}
+
+func (observer *countingObserver) Done(err error) {
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	observer.errs = append(observer.errs, err)
+}
+
+func TestRetryClient_WithObserver(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		sendError       int
+		expectedRetried int
+		expectedError   bool
+	}{
+		{
+			name:            "success",
+			sendError:       1,
+			expectedRetried: 1,
+			expectedError:   false,
+		},
+		{
+			name:            "exhausted",
+			sendError:       10,
+			expectedRetried: 2,
+			expectedError:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			fakeServer.FailNext(testCase.sendError)
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			observer := &countingObserver{}
+			lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+			retryClient := NewRetryClient(lokiClient).
+				WithBackoff(&ExponentialBackoff{Delay: time.Millisecond, Max: 2 * time.Millisecond}).
+				WithObserver(observer)
+
+			err := <-retryClient.PushWithHandle(t.Context(), client.Entry{})
+			if testCase.expectedError {
+				require.ErrorIs(t, err, &client.PushStatusError{})
+			} else {
+				require.NoError(t, err)
+			}
+
+			observer.lock.Lock()
+			defer observer.lock.Unlock()
+
+			require.Equal(t, 1, observer.queued)
+			require.Equal(t, testCase.expectedRetried, observer.retried)
+			require.Len(t, observer.errs, 1)
+			require.Equal(t, err, observer.errs[0])
+		})
+	}
+}
+
+func TestRetryClient_Flush(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.FailNext(2)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	retryClient := NewRetryClient(lokiClient).WithBackoff(&ExponentialBackoff{Delay: 10 * time.Millisecond})
+
+	require.NoError(t, retryClient.Push(t.Context(), client.Entry{}))
+	require.NoError(t, retryClient.Flush(t.Context()))
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 1, "Expected the push to have completed")
+}
+
+func TestRetryClient_FlushContextDone(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.FailNext(1)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	retryClient := NewRetryClient(lokiClient).WithBackoff(&ExponentialBackoff{Delay: time.Hour})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	handle := retryClient.PushWithHandle(ctx, client.Entry{})
+
+	flushCtx, flushCancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer flushCancel()
+
+	require.ErrorIs(t, retryClient.Flush(flushCtx), context.DeadlineExceeded)
+
+	cancel()
+	require.ErrorIs(t, <-handle, context.Canceled)
+}
+
+// blockingClient is a client.Client whose pushes block until the release channel is closed.
+type blockingClient struct {
+	release chan struct{}
+}
+
+func (blocking *blockingClient) Push(ctx context.Context, _ client.Entry) error {
+	select {
+	case <-blocking.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestRetryClient_FlushDerivedClients(t *testing.T) {
+	t.Parallel()
+
+	inner := &blockingClient{release: make(chan struct{})}
+	retryClient := NewRetryClient(inner)
+	derivedClient := retryClient.WithObserver(&countingObserver{}).WithBackoff(&ExponentialBackoff{})
+
+	handle := retryClient.PushWithHandle(t.Context(), client.Entry{})
+
+	flushCtx, flushCancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer flushCancel()
+
+	// The derived client shares the pushes in flight with the original one.
+	require.ErrorIs(t, derivedClient.Flush(flushCtx), context.DeadlineExceeded)
+
+	close(inner.release)
+
+	require.NoError(t, derivedClient.Flush(t.Context()))
+	require.NoError(t, <-handle)
+}
+
+func TestTracker_Wait(t *testing.T) {
+	t.Parallel()
+
+	tracker := newTracker()
+	first := tracker.start()
+	before := tracker.started()
+
+	// Pushes started after the snapshot, such as by other goroutines that keep logging, are not waited for.
+	later := tracker.start()
+
+	waited := make(chan error, 1)
+
+	go func() {
+		waited <- tracker.wait(context.Background(), before)
+	}()
+
+	tracker.finish(first)
+	require.NoError(t, <-waited)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, tracker.wait(ctx, tracker.started()), context.DeadlineExceeded)
+
+	tracker.finish(later)
+	require.NoError(t, tracker.wait(t.Context(), tracker.started()))
+}
+
+func TestRetryClient_NotRetryable(t *testing.T) {
+	t.Parallel()
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusBadRequest)
+		_, _ = writer.Write([]byte(`stream '{app="test"}' has label value too long: 'test'`))
+	}))
+	defer httpServer.Close()
+
+	observer := &countingObserver{}
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	retryClient := NewRetryClient(lokiClient).
+		WithBackoff(&ExponentialBackoff{Delay: time.Millisecond}).
+		WithObserver(observer)
+
+	err := <-retryClient.PushWithHandle(t.Context(), client.Entry{})
+
+	var statusErr *client.PushStatusError
+	require.ErrorAs(t, err, &statusErr)
+	require.Equal(t, client.ReasonInvalidLabels, statusErr.Reason)
+
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	require.Zero(t, observer.retried, "Expected the push not to be retried")
+}
@@ -0,0 +1,332 @@
+// Package sample provides samplers that reduce the volume of log entries sent to Loki. They are meant for hot paths
+// that log far more often than is useful and would otherwise run into Loki's ingestion limits.
+//
+// A [Sampler] decides for each entry whether it should be kept. The samplers in this package can be combined using
+// [Chain] and wrapped in a [Filter] to count how many entries were sampled out and to exempt errors from sampling. The
+// slog and logr adapters accept any Sampler through their WithSampler methods.
+//
+// Levels are always given on the [slog.Level] scale. Adapters for other logging libraries convert their levels before
+// calling a Sampler, e.g. the logr adapter maps V(n) to slog.Level(-n) and errors to [slog.LevelError].
+package sample
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tslnc04/loki-logger/pkg/client"
+)
+
+// Sampler is an interface that decides whether a log entry should be sent to Loki. Sample is called once for every
+// entry that is enabled by the logger and returns true if the entry should be kept.
+//
+// Implementations of this interface should be safe to use concurrently.
+type Sampler interface {
+	Sample(level slog.Level, entry client.Entry) bool
+}
+
+// Chain is a [Sampler] that keeps an entry only if all of its samplers keep it. The samplers are called in order and
+// evaluation stops at the first sampler that drops the entry, so stateful samplers later in the chain are not affected
+// by entries that were already dropped.
+type Chain []Sampler
+
+// Assert that Chain implements the [Sampler] interface.
+var _ Sampler = Chain(nil)
+
+// Sample implements the [Sampler] interface. An empty Chain keeps every entry.
+func (chain Chain) Sample(level slog.Level, entry client.Entry) bool {
+	for _, sampler := range chain {
+		if !sampler.Sample(level, entry) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Filter is a [Sampler] that wraps another Sampler, counting the entries that were kept and dropped. Optionally, it
+// never samples errors, i.e. entries with a level of at least [slog.LevelError] are always kept without consulting the
+// wrapped Sampler. It is safe to use concurrently.
+type Filter struct {
+	sampler    Sampler
+	keepErrors bool
+	kept       atomic.Uint64
+	dropped    atomic.Uint64
+}
+
+// Assert that Filter implements the [Sampler] interface.
+var _ Sampler = (*Filter)(nil)
+
+// NewFilter creates a new Filter wrapping the given sampler. If keepErrors is true, errors are never sampled.
+func NewFilter(sampler Sampler, keepErrors bool) *Filter {
+	return &Filter{
+		sampler:    sampler,
+		keepErrors: keepErrors,
+	}
+}
+
+// Sample implements the [Sampler] interface. It updates the counters of kept and dropped entries.
+func (filter *Filter) Sample(level slog.Level, entry client.Entry) bool {
+	keep := (filter.keepErrors && level >= slog.LevelError) || filter.sampler.Sample(level, entry)
+	if keep {
+		filter.kept.Add(1)
+	} else {
+		filter.dropped.Add(1)
+	}
+
+	return keep
+}
+
+// Kept returns the number of entries kept by the Filter so far.
+func (filter *Filter) Kept() uint64 {
+	return filter.kept.Load()
+}
+
+// Dropped returns the number of entries sampled out by the Filter so far.
+func (filter *Filter) Dropped() uint64 {
+	return filter.dropped.Load()
+}
+
+// numCounters is the number of counters used by [CountSampler]. Messages are hashed into these counters, so distinct
+// messages may occasionally share a counter.
+const numCounters = 4096
+
+// CountSampler is a [Sampler] that keeps the first N entries with a given level and message in each interval and every
+// Mth entry after that. It is modeled after the sampler in zap and, like it, hashes messages into a fixed number of
+// counters instead of tracking every message exactly. It is safe to use concurrently.
+type CountSampler struct {
+	tick       time.Duration
+	first      uint64
+	thereafter uint64
+	counters   [numCounters]counter
+}
+
+// Assert that CountSampler implements the [Sampler] interface.
+var _ Sampler = (*CountSampler)(nil)
+
+// NewCountSampler creates a new CountSampler that keeps the first entries of each message in every tick and every
+// thereafter-th entry after that. If thereafter is zero, all entries after the first are dropped until the tick ends.
+func NewCountSampler(tick time.Duration, first, thereafter uint64) *CountSampler {
+	return &CountSampler{
+		tick:       tick,
+		first:      first,
+		thereafter: thereafter,
+	}
+}
+
+// Sample implements the [Sampler] interface. It only considers the level and the line of the entry.
+func (sampler *CountSampler) Sample(level slog.Level, entry client.Entry) bool {
+	messageCounter := &sampler.counters[hashMessage(level, entry.Line)%numCounters]
+
+	count := messageCounter.incCheckReset(time.Now(), sampler.tick)
+	if count <= sampler.first {
+		return true
+	}
+
+	if sampler.thereafter == 0 {
+		return false
+	}
+
+	return (count-sampler.first)%sampler.thereafter == 0
+}
+
+// counter counts the entries seen within the current interval, which ends at resetAt.
+type counter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// incCheckReset increments the counter and returns the new count. If the current interval has ended, the counter is
+// reset and a new interval of length tick is started.
+func (counter *counter) incCheckReset(now time.Time, tick time.Duration) uint64 {
+	// This follows the counter in zap's sampler, which is licensed under the MIT license.
+	nowNano := now.UnixNano()
+
+	resetAt := counter.resetAt.Load()
+	if resetAt > nowNano {
+		return counter.count.Add(1)
+	}
+
+	counter.count.Store(1)
+
+	if !counter.resetAt.CompareAndSwap(resetAt, nowNano+tick.Nanoseconds()) {
+		return counter.count.Add(1)
+	}
+
+	return 1
+}
+
+// hashMessage hashes the level and message using 32-bit FNV-1a. It is inlined to avoid allocating a hash.Hash32.
+func hashMessage(level slog.Level, message string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+
+	hash := uint32(offset32)
+	hash ^= uint32(level)
+	hash *= prime32
+
+	for i := range len(message) {
+		hash ^= uint32(message[i])
+		hash *= prime32
+	}
+
+	return hash
+}
+
+// levelRate is the probability of keeping entries at or below the level.
+type levelRate struct {
+	level slog.Level
+	rate  float64
+}
+
+// RandomSampler is a [Sampler] that keeps entries with a probability depending on their level. It is safe to use
+// concurrently.
+//
+// Each configured rate applies to entries at or below its level, down to the next lower configured level. Entries above
+// the highest configured level are always kept. For example, with rates of 0.01 for [slog.LevelDebug] and 0.1 for
+// [slog.LevelInfo], 1% of debug entries (including those below debug) and 10% of info entries are kept, while warnings
+// and errors are not sampled.
+type RandomSampler struct {
+	// rates is sorted by level in ascending order.
+	rates []levelRate
+}
+
+// Assert that RandomSampler implements the [Sampler] interface.
+var _ Sampler = (*RandomSampler)(nil)
+
+// NewRandomSampler creates a new RandomSampler with the given rates by level. Rates are clamped to the range [0, 1].
+func NewRandomSampler(rates map[slog.Level]float64) *RandomSampler {
+	levelRates := make([]levelRate, 0, len(rates))
+
+	for level, rate := range rates {
+		levelRates = append(levelRates, levelRate{level: level, rate: min(max(rate, 0), 1)})
+	}
+
+	slices.SortFunc(levelRates, func(left, right levelRate) int {
+		return int(left.level) - int(right.level)
+	})
+
+	return &RandomSampler{
+		rates: levelRates,
+	}
+}
+
+// Sample implements the [Sampler] interface. It only considers the level of the entry.
+func (sampler *RandomSampler) Sample(level slog.Level, _ client.Entry) bool {
+	for _, levelRate := range sampler.rates {
+		if level <= levelRate.level {
+			return rand.Float64() < levelRate.rate
+		}
+	}
+
+	return true
+}
+
+// minSweepInterval is the minimum time between two sweeps of idle buckets by [RateLimiter], which bounds the cost of
+// sweeping for high rates where buckets refill quickly.
+const minSweepInterval = time.Second
+
+// RateLimiter is a [Sampler] that limits the rate of entries per stream using a token bucket. Each distinct set of
+// stream labels has its own bucket which holds up to burst tokens and refills at rate tokens per second. Every kept
+// entry consumes one token. It is safe to use concurrently.
+//
+// Buckets that have not been used for long enough to refill completely are removed, since they behave the same as new
+// buckets. This keeps the memory bounded by the number of streams active within the refill time, even for labels with
+// many distinct values. If the rate is zero, buckets never refill and are thus never removed.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+	// refillTime is how long it takes for an empty bucket to refill completely.
+	refillTime time.Duration
+	lock       *sync.Mutex
+	buckets    map[client.LabelString]*bucket
+	// swept is the time of the last sweep of idle buckets.
+	swept time.Time
+}
+
+// Assert that RateLimiter implements the [Sampler] interface.
+var _ Sampler = (*RateLimiter)(nil)
+
+// NewRateLimiter creates a new RateLimiter allowing rate entries per second with bursts of up to burst entries for
+// each stream.
+func NewRateLimiter(rate float64, burst uint) *RateLimiter {
+	limiter := &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		lock:    &sync.Mutex{},
+		buckets: make(map[client.LabelString]*bucket),
+	}
+
+	if rate > 0 {
+		limiter.refillTime = time.Duration(float64(burst) / rate * float64(time.Second))
+	}
+
+	return limiter
+}
+
+// Sample implements the [Sampler] interface. It only considers the labels of the entry.
+func (limiter *RateLimiter) Sample(_ slog.Level, entry client.Entry) bool {
+	labels := client.LabelString("{}")
+	if entry.Labels != nil {
+		labels = entry.Labels.Label()
+	}
+
+	return limiter.sample(time.Now(), labels)
+}
+
+// sample takes a token from the bucket of the stream at the given time, creating the bucket if necessary.
+func (limiter *RateLimiter) sample(now time.Time, labels client.LabelString) bool {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	limiter.sweep(now)
+
+	streamBucket, ok := limiter.buckets[labels]
+	if !ok {
+		streamBucket = &bucket{tokens: limiter.burst, last: now}
+		limiter.buckets[labels] = streamBucket
+	}
+
+	return streamBucket.take(now, limiter.rate, limiter.burst)
+}
+
+// sweep removes the buckets that have refilled completely. It iterates over all buckets, so it only runs once per
+// refill time, but at most once per minSweepInterval. It must only be called while holding the lock.
+func (limiter *RateLimiter) sweep(now time.Time) {
+	if limiter.rate <= 0 || now.Sub(limiter.swept) < max(limiter.refillTime, minSweepInterval) {
+		return
+	}
+
+	limiter.swept = now
+
+	for labels, streamBucket := range limiter.buckets {
+		if now.Sub(streamBucket.last) >= limiter.refillTime {
+			delete(limiter.buckets, labels)
+		}
+	}
+}
+
+// bucket is a token bucket for a single stream. It must only be used while holding the lock of its RateLimiter.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket for the time passed since the last call and then takes a single token if available.
+func (bucket *bucket) take(now time.Time, rate, burst float64) bool {
+	bucket.tokens = min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+
+	return true
+}
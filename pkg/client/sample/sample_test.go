@@ -0,0 +1,275 @@
+package sample
+
+import (
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+)
+
+var testEntry = client.Entry{
+	Labels: client.LabelMap{"foo": "bar"},
+	Line:   "test message",
+}
+
+// constSampler is a Sampler that always returns the same decision.
+type constSampler bool
+
+func (sampler constSampler) Sample(slog.Level, client.Entry) bool {
+	return bool(sampler)
+}
+
+// countKept returns the number of times the sampler keeps the entry out of the given number of calls.
+func countKept(sampler Sampler, level slog.Level, entry client.Entry, calls int) int {
+	kept := 0
+
+	for range calls {
+		if sampler.Sample(level, entry) {
+			kept++
+		}
+	}
+
+	return kept
+}
+
+func TestChain_Sample(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		chain    Chain
+		expected bool
+	}{
+		{
+			name:     "empty",
+			chain:    Chain{},
+			expected: true,
+		},
+		{
+			name:     "all-keep",
+			chain:    Chain{constSampler(true), constSampler(true)},
+			expected: true,
+		},
+		{
+			name:     "one-drops",
+			chain:    Chain{constSampler(true), constSampler(false)},
+			expected: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testCase.expected, testCase.chain.Sample(slog.LevelInfo, testEntry))
+		})
+	}
+}
+
+func TestChain_SampleShortCircuits(t *testing.T) {
+	t.Parallel()
+
+	limiter := NewRateLimiter(0, 1)
+	chain := Chain{constSampler(false), limiter}
+
+	require.False(t, chain.Sample(slog.LevelInfo, testEntry))
+
+	// The limiter was never consulted, so its single token is still available.
+	require.True(t, limiter.Sample(slog.LevelInfo, testEntry))
+}
+
+func TestFilter_Sample(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		keepErrors      bool
+		level           slog.Level
+		expectedKept    uint64
+		expectedDropped uint64
+	}{
+		{
+			name:            "info-sampled",
+			keepErrors:      true,
+			level:           slog.LevelInfo,
+			expectedKept:    0,
+			expectedDropped: 3,
+		},
+		{
+			name:            "errors-kept",
+			keepErrors:      true,
+			level:           slog.LevelError,
+			expectedKept:    3,
+			expectedDropped: 0,
+		},
+		{
+			name:            "errors-sampled",
+			keepErrors:      false,
+			level:           slog.LevelError,
+			expectedKept:    0,
+			expectedDropped: 3,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			filter := NewFilter(constSampler(false), testCase.keepErrors)
+			countKept(filter, testCase.level, testEntry, 3)
+
+			require.Equal(t, testCase.expectedKept, filter.Kept())
+			require.Equal(t, testCase.expectedDropped, filter.Dropped())
+		})
+	}
+}
+
+func TestCountSampler_Sample(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		first      uint64
+		thereafter uint64
+		calls      int
+		expected   int
+	}{
+		{
+			name:       "first-only",
+			first:      3,
+			thereafter: 0,
+			calls:      10,
+			expected:   3,
+		},
+		{
+			name:       "first-then-every-third",
+			first:      2,
+			thereafter: 3,
+			calls:      11,
+			expected:   5,
+		},
+		{
+			name:       "below-first",
+			first:      5,
+			thereafter: 10,
+			calls:      4,
+			expected:   4,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			sampler := NewCountSampler(time.Minute, testCase.first, testCase.thereafter)
+			kept := countKept(sampler, slog.LevelInfo, testEntry, testCase.calls)
+
+			require.Equal(t, testCase.expected, kept)
+		})
+	}
+}
+
+func TestCountSampler_SamplePerMessage(t *testing.T) {
+	t.Parallel()
+
+	sampler := NewCountSampler(time.Minute, 1, 0)
+	otherEntry := client.Entry{Line: "other message"}
+
+	require.True(t, sampler.Sample(slog.LevelInfo, testEntry))
+	require.False(t, sampler.Sample(slog.LevelInfo, testEntry))
+	require.True(t, sampler.Sample(slog.LevelInfo, otherEntry))
+	require.True(t, sampler.Sample(slog.LevelWarn, testEntry))
+}
+
+func TestCountSampler_SampleResets(t *testing.T) {
+	t.Parallel()
+
+	sampler := NewCountSampler(50*time.Millisecond, 1, 0)
+
+	require.True(t, sampler.Sample(slog.LevelInfo, testEntry))
+	require.False(t, sampler.Sample(slog.LevelInfo, testEntry))
+
+	time.Sleep(60 * time.Millisecond)
+
+	require.True(t, sampler.Sample(slog.LevelInfo, testEntry))
+}
+
+func TestRandomSampler_Sample(t *testing.T) {
+	t.Parallel()
+
+	sampler := NewRandomSampler(map[slog.Level]float64{
+		slog.LevelDebug: 0,
+		slog.LevelInfo:  1.5,
+		slog.LevelWarn:  0.5,
+	})
+
+	const calls = 1000
+
+	require.Zero(t, countKept(sampler, slog.LevelDebug-4, testEntry, calls))
+	require.Zero(t, countKept(sampler, slog.LevelDebug, testEntry, calls))
+	require.Equal(t, calls, countKept(sampler, slog.LevelInfo, testEntry, calls))
+	require.InDelta(t, calls/2, countKept(sampler, slog.LevelWarn, testEntry, calls), calls/10)
+	require.Equal(t, calls, countKept(sampler, slog.LevelError, testEntry, calls))
+}
+
+func TestRateLimiter_Sample(t *testing.T) {
+	t.Parallel()
+
+	limiter := NewRateLimiter(0, 2)
+	otherEntry := client.Entry{Labels: client.LabelMap{"foo": "baz"}}
+
+	require.Equal(t, 2, countKept(limiter, slog.LevelInfo, testEntry, 5))
+	require.Equal(t, 2, countKept(limiter, slog.LevelInfo, otherEntry, 5))
+	require.Equal(t, 2, countKept(limiter, slog.LevelInfo, client.Entry{}, 5))
+}
+
+func TestRateLimiter_SampleRefills(t *testing.T) {
+	t.Parallel()
+
+	limiter := NewRateLimiter(100, 1)
+
+	require.True(t, limiter.Sample(slog.LevelInfo, testEntry))
+	require.False(t, limiter.Sample(slog.LevelInfo, testEntry))
+
+	time.Sleep(20 * time.Millisecond)
+
+	require.True(t, limiter.Sample(slog.LevelInfo, testEntry))
+}
+
+func TestRateLimiter_SampleEvictsIdleBuckets(t *testing.T) {
+	t.Parallel()
+
+	// The buckets refill completely within 10 seconds.
+	limiter := NewRateLimiter(0.1, 1)
+	now := time.Now()
+
+	for index := range 100 {
+		require.True(t, limiter.sample(now, client.LabelString(`{id="`+strconv.Itoa(index)+`"}`)))
+	}
+
+	require.False(t, limiter.sample(now, `{id="0"}`))
+	require.Len(t, limiter.buckets, 100)
+
+	// Buckets used within the refill time are kept, since they are not full yet.
+	require.False(t, limiter.sample(now.Add(5*time.Second), `{id="0"}`))
+	require.Len(t, limiter.buckets, 100)
+
+	require.True(t, limiter.sample(now.Add(11*time.Second), `{id="1"}`))
+	require.Len(t, limiter.buckets, 2)
+	require.Contains(t, limiter.buckets, client.LabelString(`{id="0"}`))
+	require.Contains(t, limiter.buckets, client.LabelString(`{id="1"}`))
+}
+
+func TestRateLimiter_SampleKeepsBucketsWithoutRate(t *testing.T) {
+	t.Parallel()
+
+	limiter := NewRateLimiter(0, 1)
+	now := time.Now()
+
+	require.True(t, limiter.sample(now, `{id="0"}`))
+	require.False(t, limiter.sample(now.Add(time.Hour), `{id="0"}`))
+	require.Len(t, limiter.buckets, 1)
+}
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrInvalidTLSOptions is returned by [NewTLSConfig] and [LokiClient.WithTLS] if the [TLSOptions] are inconsistent.
+var ErrInvalidTLSOptions = errors.New("invalid TLS options")
+
+// TLSOptions configures the TLS connections to Loki, see [NewTLSConfig]. All files are PEM-encoded and reloaded
+// automatically when they change, so that certificates rotated on disk, e.g. by cert-manager, are picked up by new
+// connections without restarting the application.
+type TLSOptions struct {
+	// CertFile is the client certificate presented to Loki for mutual TLS. It must be set together with KeyFile.
+	CertFile string
+	// KeyFile is the private key of the client certificate.
+	KeyFile string
+	// CAFile is the bundle of certificate authorities used to verify Loki's certificate. If empty, the system's
+	// certificate authorities are used.
+	CAFile string
+	// ServerName is the name used to verify Loki's certificate and sent in the SNI extension. [LokiClient.WithTLS]
+	// defaults it to the host of the URL, but [NewTLSConfig] requires it if CAFile is set.
+	ServerName string
+	// MinVersion is the minimum TLS version, such as [tls.VersionTLS13]. If zero, the default of [crypto/tls] is used.
+	MinVersion uint16
+}
+
+// NewTLSConfig returns a [tls.Config] for connecting to Loki with the given options. The files are loaded once to
+// report errors early, and then again whenever their modification time changes. If reloading fails, e.g. while the
+// files are being replaced, the previously loaded certificates are used until the next attempt succeeds.
+//
+// If CAFile is set, Loki's certificate is verified in [tls.Config.VerifyConnection] using the current bundle, since
+// the fixed RootCAs of the config could not be reloaded. The verification is otherwise the same as the default one.
+// ServerName must be set in that case, since the host being dialed is not known to VerifyConnection.
+func NewTLSConfig(options TLSOptions) (*tls.Config, error) {
+	if (options.CertFile == "") != (options.KeyFile == "") {
+		return nil, fmt.Errorf("%w: CertFile and KeyFile must be set together", ErrInvalidTLSOptions)
+	}
+
+	if options.CAFile != "" && options.ServerName == "" {
+		return nil, fmt.Errorf("%w: ServerName must be set together with CAFile", ErrInvalidTLSOptions)
+	}
+
+	config := &tls.Config{
+		ServerName: options.ServerName,
+		MinVersion: options.MinVersion,
+	}
+
+	if options.CertFile != "" {
+		certificate := newFileReloader(func() (*tls.Certificate, error) {
+			certificate, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+			if err != nil {
+				return nil, err
+			}
+
+			return &certificate, nil
+		}, options.CertFile, options.KeyFile)
+
+		if _, err := certificate.get(); err != nil {
+			return nil, err
+		}
+
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certificate.get()
+		}
+	}
+
+	if options.CAFile != "" {
+		roots := newFileReloader(func() (*x509.CertPool, error) {
+			return loadCertPool(options.CAFile)
+		}, options.CAFile)
+
+		if _, err := roots.get(); err != nil {
+			return nil, err
+		}
+
+		// The certificate is still verified, just by VerifyConnection instead of the handshake.
+		config.InsecureSkipVerify = true //nolint:gosec // Verified by VerifyConnection using the reloaded bundle.
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			pool, err := roots.get()
+			if err != nil {
+				return err
+			}
+
+			return verifyConnection(state, pool, options.ServerName)
+		}
+	}
+
+	return config, nil
+}
+
+// WithTLS sets the TLS options of the connections to Loki, see [TLSOptions]. If ServerName is empty, it defaults to the
+// host of the URL. Like all transport options, it modifies a copy of the transport, see [LokiClient.WithProxy]. It is
+// safe to call concurrently from multiple goroutines as it returns a new LokiClient struct.
+func (client *LokiClient) WithTLS(options TLSOptions) (*LokiClient, error) {
+	if options.ServerName == "" {
+		parsed, err := url.Parse(client.url)
+		if err != nil {
+			return nil, err
+		}
+
+		options.ServerName = parsed.Hostname()
+	}
+
+	config, err := NewTLSConfig(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.withTransport(func(transport *http.Transport) {
+		transport.TLSClientConfig = config
+	}), nil
+}
+
+// verifyConnection verifies the certificate of the server against the roots and the server name, the same way as the
+// TLS handshake does by default. The server name of the connection state cannot be used, since it is empty for IP
+// addresses, which are not sent using SNI.
+func verifyConnection(state tls.ConnectionState, roots *x509.CertPool, serverName string) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("tls: server did not provide a certificate")
+	}
+
+	options := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+
+	for _, certificate := range state.PeerCertificates[1:] {
+		options.Intermediates.AddCert(certificate)
+	}
+
+	if _, err := state.PeerCertificates[0].Verify(options); err != nil {
+		return &tls.CertificateVerificationError{UnverifiedCertificates: state.PeerCertificates, Err: err}
+	}
+
+	return nil
+}
+
+// loadCertPool loads the PEM-encoded certificates in the file into a new pool.
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+
+	return pool, nil
+}
+
+// fileReloader holds a value loaded from files and loads it again when the modification time of any of them changes.
+// It is safe to use concurrently from multiple goroutines.
+type fileReloader[T any] struct {
+	files []string
+	load  func() (T, error)
+
+	lock     sync.Mutex
+	modTimes []time.Time
+	value    T
+}
+
+func newFileReloader[T any](load func() (T, error), files ...string) *fileReloader[T] {
+	return &fileReloader[T]{
+		files: files,
+		load:  load,
+	}
+}
+
+// get returns the current value, reloading it if the files have changed. If the files cannot be loaded, the previous
+// value is returned if there is one, and loading is attempted again on the next call.
+func (reloader *fileReloader[T]) get() (T, error) {
+	reloader.lock.Lock()
+	defer reloader.lock.Unlock()
+
+	loaded := reloader.modTimes != nil
+
+	modTimes := make([]time.Time, len(reloader.files))
+
+	for index, file := range reloader.files {
+		info, err := os.Stat(file)
+		if err != nil {
+			if loaded {
+				return reloader.value, nil
+			}
+
+			var zero T
+
+			return zero, err
+		}
+
+		modTimes[index] = info.ModTime()
+	}
+
+	if loaded && slices.EqualFunc(modTimes, reloader.modTimes, time.Time.Equal) {
+		return reloader.value, nil
+	}
+
+	value, err := reloader.load()
+	if err != nil && loaded {
+		return reloader.value, nil
+	} else if err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	reloader.value = value
+	reloader.modTimes = modTimes
+
+	return value, nil
+}
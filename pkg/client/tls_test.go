@@ -0,0 +1,304 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+// testCertificate is a certificate generated for a test along with its key.
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+// newTestCertificate generates a certificate with the serial number, signed by the parent or self-signed if it is nil.
+// Unless it is self-signed as a CA, it is a client certificate, or a server certificate for the DNS names if there are
+// any.
+func newTestCertificate(t *testing.T, serial int64, parent *testCertificate, dnsNames ...string) *testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "loki-logger test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	if len(dnsNames) > 0 {
+		template.DNSNames = dnsNames
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+
+	signer := &testCertificate{certificate: template, key: key}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.ExtKeyUsage = nil
+	} else {
+		signer = parent
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer.certificate, &key.PublicKey, signer.key)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCertificate{certificate: certificate, key: key}
+}
+
+// writeFiles writes the certificate and key as PEM files, setting their modification time to the given one so that a
+// rewrite is detected even if the file system has a coarse timestamp resolution.
+func (certificate *testCertificate) writeFiles(t *testing.T, certFile, keyFile string, modTime time.Time) {
+	t.Helper()
+
+	writePEM(t, certFile, "CERTIFICATE", certificate.certificate.Raw, modTime)
+
+	der, err := x509.MarshalPKCS8PrivateKey(certificate.key)
+	require.NoError(t, err)
+
+	writePEM(t, keyFile, "PRIVATE KEY", der, modTime)
+}
+
+// writePEM writes the block as a PEM file with the given modification time.
+func writePEM(t *testing.T, file, blockType string, der []byte, modTime time.Time) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	require.NoError(t, os.Chtimes(file, modTime, modTime))
+}
+
+func TestLokiClient_WithTLS(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := httptest.NewTLSServer(fakeServer)
+
+	defer httpServer.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	writePEM(t, caFile, "CERTIFICATE", httpServer.Certificate().Raw, time.Now())
+
+	entry := Entry{
+		Timestamp: time.Now(),
+		Labels:    LabelMap{"foo": "bar"},
+		Line:      "test message",
+	}
+
+	lokiClient, err := NewLokiClient(httpServer.URL + PushPath).WithTLS(TLSOptions{CAFile: caFile})
+	require.NoError(t, err)
+	require.NoError(t, lokiClient.Push(t.Context(), entry))
+
+	// The certificate of httptest is valid for example.com, but not for other names.
+	lokiClient, err = NewLokiClient(httpServer.URL + PushPath).WithTLS(TLSOptions{
+		CAFile:     caFile,
+		ServerName: "example.com",
+	})
+	require.NoError(t, err)
+	require.NoError(t, lokiClient.Push(t.Context(), entry))
+
+	lokiClient, err = NewLokiClient(httpServer.URL + PushPath).WithTLS(TLSOptions{
+		CAFile:     caFile,
+		ServerName: "example.org",
+	})
+	require.NoError(t, err)
+
+	var verificationErr *tls.CertificateVerificationError
+	require.ErrorAs(t, lokiClient.Push(t.Context(), entry), &verificationErr)
+
+	streams := fakeServer.Streams()
+	require.Len(t, streams, 2)
+	AssertStreamMatchesEntry(t, entry, streams[0])
+	AssertStreamMatchesEntry(t, entry, streams[1])
+}
+
+func TestLokiClient_WithTLS_IPAddress(t *testing.T) {
+	t.Parallel()
+
+	serverCA := newTestCertificate(t, 1, nil)
+	serverCertificate := newTestCertificate(t, 2, serverCA, "loki.example")
+
+	fakeServer := lokitest.NewServer()
+	httpServer := httptest.NewUnstartedServer(fakeServer)
+	httpServer.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{serverCertificate.certificate.Raw},
+			PrivateKey:  serverCertificate.key,
+		}},
+		MinVersion: tls.VersionTLS12,
+	}
+	httpServer.StartTLS()
+
+	defer httpServer.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	writePEM(t, caFile, "CERTIFICATE", serverCA.certificate.Raw, time.Now())
+
+	entry := Entry{
+		Timestamp: time.Now(),
+		Labels:    LabelMap{"foo": "bar"},
+		Line:      "test message",
+	}
+
+	// The URL is an IP address, which the certificate for another name is not valid for.
+	lokiClient, err := NewLokiClient(httpServer.URL + PushPath).WithTLS(TLSOptions{CAFile: caFile})
+	require.NoError(t, err)
+
+	var hostnameErr x509.HostnameError
+	require.ErrorAs(t, lokiClient.Push(t.Context(), entry), &hostnameErr)
+
+	lokiClient, err = NewLokiClient(httpServer.URL + PushPath).WithTLS(TLSOptions{
+		CAFile:     caFile,
+		ServerName: "loki.example",
+	})
+	require.NoError(t, err)
+	require.NoError(t, lokiClient.Push(t.Context(), entry))
+
+	streams := fakeServer.Streams()
+	require.Len(t, streams, 1)
+	AssertStreamMatchesEntry(t, entry, streams[0])
+}
+
+func TestLokiClient_WithTLS_MinVersion(t *testing.T) {
+	t.Parallel()
+
+	httpServer := httptest.NewUnstartedServer(lokitest.NewServer())
+	httpServer.TLS = &tls.Config{MaxVersion: tls.VersionTLS12} //nolint:gosec // Testing the minimum version.
+	httpServer.StartTLS()
+
+	defer httpServer.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	writePEM(t, caFile, "CERTIFICATE", httpServer.Certificate().Raw, time.Now())
+
+	entry := Entry{
+		Timestamp: time.Now(),
+		Labels:    LabelMap{"foo": "bar"},
+		Line:      "test message",
+	}
+
+	lokiClient, err := NewLokiClient(httpServer.URL + PushPath).WithTLS(TLSOptions{CAFile: caFile})
+	require.NoError(t, err)
+	require.NoError(t, lokiClient.Push(t.Context(), entry))
+
+	lokiClient, err = NewLokiClient(httpServer.URL + PushPath).WithTLS(TLSOptions{
+		CAFile:     caFile,
+		MinVersion: tls.VersionTLS13,
+	})
+	require.NoError(t, err)
+	require.ErrorContains(t, lokiClient.Push(t.Context(), entry), "protocol version")
+}
+
+func TestLokiClient_WithTLS_Reload(t *testing.T) { //nolint:funlen // Rotates both the CA and the client certificate.
+	t.Parallel()
+
+	clientCA := newTestCertificate(t, 1, nil)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA.certificate)
+
+	fakeServer := lokitest.NewServer()
+	serials := make(chan int64, 1)
+	httpServer := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		serials <- request.TLS.PeerCertificates[0].SerialNumber.Int64()
+
+		fakeServer.ServeHTTP(writer, request)
+	}))
+	httpServer.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+	httpServer.StartTLS()
+
+	defer httpServer.Close()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+	modTime := time.Now().Add(-time.Minute)
+
+	newTestCertificate(t, 2, clientCA).writeFiles(t, certFile, keyFile, modTime)
+
+	// Start with an unrelated CA, so that the server is not trusted until the bundle is replaced.
+	writePEM(t, caFile, "CERTIFICATE", clientCA.certificate.Raw, modTime)
+
+	entry := Entry{
+		Timestamp: time.Now(),
+		Labels:    LabelMap{"foo": "bar"},
+		Line:      "test message",
+	}
+
+	lokiClient, err := NewLokiClient(httpServer.URL + PushPath).WithTLS(TLSOptions{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		CAFile:   caFile,
+	})
+	require.NoError(t, err)
+
+	var verificationErr *tls.CertificateVerificationError
+	require.ErrorAs(t, lokiClient.Push(t.Context(), entry), &verificationErr)
+
+	writePEM(t, caFile, "CERTIFICATE", httpServer.Certificate().Raw, modTime.Add(time.Second))
+
+	require.NoError(t, lokiClient.Push(t.Context(), entry))
+	require.Equal(t, int64(2), <-serials)
+
+	// Rotate the client certificate. Only new connections use it, so close the idle one.
+	newTestCertificate(t, 3, clientCA).writeFiles(t, certFile, keyFile, modTime.Add(2*time.Second))
+	lokiClient.client.CloseIdleConnections()
+
+	require.NoError(t, lokiClient.Push(t.Context(), entry))
+	require.Equal(t, int64(3), <-serials)
+
+	// A broken rotation keeps the previous certificate.
+	require.NoError(t, os.WriteFile(keyFile, []byte("invalid"), 0o600))
+	require.NoError(t, os.Chtimes(keyFile, modTime.Add(3*time.Second), modTime.Add(3*time.Second)))
+	lokiClient.client.CloseIdleConnections()
+
+	require.NoError(t, lokiClient.Push(t.Context(), entry))
+	require.Equal(t, int64(3), <-serials)
+
+	require.Len(t, fakeServer.Streams(), 3)
+}
+
+func TestNewTLSConfig_Invalid(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	emptyFile := filepath.Join(dir, "empty.pem")
+	require.NoError(t, os.WriteFile(emptyFile, nil, 0o600))
+
+	_, err := NewTLSConfig(TLSOptions{CertFile: "tls.crt"})
+	require.ErrorIs(t, err, ErrInvalidTLSOptions)
+
+	_, err = NewTLSConfig(TLSOptions{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")})
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// The name to verify against is unknown without ServerName.
+	_, err = NewTLSConfig(TLSOptions{CAFile: emptyFile})
+	require.ErrorIs(t, err, ErrInvalidTLSOptions)
+
+	_, err = NewTLSConfig(TLSOptions{CAFile: emptyFile, ServerName: "localhost"})
+	require.EqualError(t, err, "no certificates found in "+emptyFile)
+}
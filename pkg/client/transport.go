@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultPushTimeout is the timeout of a push whose context has no deadline, unless changed using
+// [LokiClient.WithTimeout]. It prevents an unresponsive Loki instance from blocking the logging goroutines forever.
+const DefaultPushTimeout = 10 * time.Second
+
+const (
+	// dialTimeout is the maximum time to establish a connection to Loki.
+	dialTimeout = 5 * time.Second
+	// keepAlive is the interval of TCP keep-alive probes, detecting connections that were silently dropped.
+	keepAlive = 30 * time.Second
+	// maxIdleConnsPerHost is the number of idle connections kept open to Loki. Pushes are small, frequent, and all go to
+	// the same host, so the default of 2 would cause connections to be closed and reopened under concurrent logging.
+	maxIdleConnsPerHost = 32
+	// idleConnTimeout is how long idle connections are kept open.
+	idleConnTimeout = 90 * time.Second
+	// tlsHandshakeTimeout is the maximum time of a TLS handshake.
+	tlsHandshakeTimeout = 5 * time.Second
+)
+
+// NewTransport returns an [http.Transport] tuned for pushing to Loki, which is used by [NewLokiClient]. Compared to
+// [http.DefaultTransport], it keeps more idle connections open, since all pushes go to the same host, and uses shorter
+// timeouts for connecting. Like the default transport, it uses the proxy from the environment and HTTP/2 for HTTPS.
+//
+// The result can be modified before passing it to [LokiClient.WithHTTPClient], e.g. to change the TLS configuration.
+func NewTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: keepAlive,
+	}
+
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          maxIdleConnsPerHost,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// NewH2CTransport returns a transport like [NewTransport] that uses HTTP/2 with prior knowledge, also known as h2c, for
+// unencrypted connections. It is meant for gateways in front of Loki that only accept h2c, such as gRPC-oriented
+// proxies. Since HTTP/1.1 is disabled, HTTPS connections also require the server to support HTTP/2.
+func NewH2CTransport() *http.Transport {
+	transport := NewTransport()
+	transport.Protocols = &http.Protocols{}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	transport.Protocols.SetHTTP2(true)
+
+	return transport
+}
+
+// WithProxy sets the URL of the proxy that requests are sent through, instead of the proxy from the environment
+// variables used by default. Requests to HTTPS URLs are tunneled using CONNECT. If the URL is nil, no proxy is used,
+// regardless of the environment.
+//
+// The current transport is cloned if it is an [*http.Transport] and replaced by the one returned by [NewTransport]
+// otherwise, so that other settings of the HTTP client, such as its timeout, are kept. It is safe to call concurrently
+// from multiple goroutines as it returns a new LokiClient struct.
+func (client *LokiClient) WithProxy(proxyURL *url.URL) *LokiClient {
+	return client.withTransport(func(transport *http.Transport) {
+		transport.Proxy = nil
+		if proxyURL != nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	})
+}
+
+// WithUnixSocket connects to Loki through the Unix domain socket at the given path, which may be prefixed with
+// unix://, instead of the host of the URL. The URL is still used for the request, so it should be a regular HTTP URL
+// such as http://loki/loki/api/v1/push, whose host is only sent in the Host header. Since the connection does not
+// leave the machine, the proxy is disabled.
+//
+// Like all transport options, it modifies a copy of the transport, see [LokiClient.WithProxy]. It is safe to call
+// concurrently from multiple goroutines as it returns a new LokiClient struct.
+func (client *LokiClient) WithUnixSocket(socket string) *LokiClient {
+	socket = strings.TrimPrefix(socket, "unix://")
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	return client.withTransport(func(transport *http.Transport) {
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	})
+}
+
+// withTransport returns a new LokiClient whose HTTP client is a copy of the current one, with a clone of its transport
+// modified by the function. If the transport is not an [*http.Transport], one returned by [NewTransport] is used.
+func (client *LokiClient) withTransport(modify func(transport *http.Transport)) *LokiClient {
+	transport, ok := client.client.Transport.(*http.Transport)
+	if ok {
+		transport = transport.Clone()
+	} else {
+		transport = NewTransport()
+	}
+
+	modify(transport)
+
+	httpClient := *client.client
+	httpClient.Transport = transport
+
+	return client.WithHTTPClient(&httpClient)
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+func TestLokiClient_WithTimeout(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		timeout       time.Duration
+		deadline      time.Duration
+		expectedError error
+	}{
+		{name: "exceeded", timeout: 10 * time.Millisecond, expectedError: context.DeadlineExceeded},
+		{name: "disabled", timeout: 0},
+		{name: "longer", timeout: time.Second},
+		{name: "context deadline", timeout: 10 * time.Millisecond, deadline: time.Second},
+	}
+
+	entry := Entry{
+		Timestamp: time.Now(),
+		Labels:    LabelMap{"foo": "bar"},
+		Line:      "test message",
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			fakeServer.SetLatency(100 * time.Millisecond)
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			ctx := t.Context()
+
+			if testCase.deadline > 0 {
+				var cancel context.CancelFunc
+
+				ctx, cancel = context.WithTimeout(ctx, testCase.deadline)
+				defer cancel()
+			}
+
+			lokiClient := NewLokiClient(httpServer.URL + PushPath).WithTimeout(testCase.timeout)
+			err := lokiClient.Push(ctx, entry)
+
+			if testCase.expectedError != nil {
+				// The server may still store the entry, since it does not notice that the client gave up.
+				require.ErrorIs(t, err, testCase.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			streams := fakeServer.Streams()
+			require.Len(t, streams, 1)
+			AssertStreamMatchesEntry(t, entry, streams[0])
+		})
+	}
+}
+
+func TestNewH2CTransport(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	protoMajors := make(chan int, 1)
+	httpServer := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		protoMajors <- request.ProtoMajor
+
+		fakeServer.ServeHTTP(writer, request)
+	}))
+	httpServer.Config.Protocols = &http.Protocols{}
+	httpServer.Config.Protocols.SetUnencryptedHTTP2(true)
+	httpServer.Start()
+
+	defer httpServer.Close()
+
+	entry := Entry{
+		Timestamp: time.Now(),
+		Labels:    LabelMap{"foo": "bar"},
+		Line:      "test message",
+	}
+
+	lokiClient := NewLokiClient(httpServer.URL + PushPath).WithHTTPClient(&http.Client{Transport: NewH2CTransport()})
+	require.NoError(t, lokiClient.Push(t.Context(), entry))
+	require.Equal(t, 2, <-protoMajors)
+
+	streams := fakeServer.Streams()
+	require.Len(t, streams, 1)
+	AssertStreamMatchesEntry(t, entry, streams[0])
+}
+
+func TestLokiClient_WithProxy(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := httptest.NewTLSServer(fakeServer)
+
+	defer httpServer.Close()
+
+	// The proxy only supports CONNECT, tunneling the connection to the requested host.
+	connects := make(chan string, 1)
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodConnect {
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		connects <- request.Host
+
+		upstream, err := net.Dial("tcp", request.Host)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadGateway)
+
+			return
+		}
+		defer upstream.Close()
+
+		conn, _, err := http.NewResponseController(writer).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+
+		go func() { _, _ = io.Copy(upstream, conn) }()
+
+		_, _ = io.Copy(conn, upstream)
+	}))
+
+	defer proxyServer.Close()
+
+	proxyURL, err := url.Parse(proxyServer.URL)
+	require.NoError(t, err)
+
+	entry := Entry{
+		Timestamp: time.Now(),
+		Labels:    LabelMap{"foo": "bar"},
+		Line:      "test message",
+	}
+
+	// The client of httptest trusts the certificate of the server, which is kept when setting the proxy.
+	lokiClient := NewLokiClient(httpServer.URL + PushPath).WithHTTPClient(httpServer.Client()).WithProxy(proxyURL)
+	require.NoError(t, lokiClient.Push(t.Context(), entry))
+	require.Equal(t, httpServer.Listener.Addr().String(), <-connects)
+
+	streams := fakeServer.Streams()
+	require.Len(t, streams, 1)
+	AssertStreamMatchesEntry(t, entry, streams[0])
+}
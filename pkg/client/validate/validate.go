@@ -0,0 +1,362 @@
+// Package validate provides a thin wrapper around the [client.Client] interface that checks entries against Loki's
+// ingestion limits before they are pushed.
+//
+// Loki rejects entries with lines that are too long, too many or too long labels, too much structured metadata, or
+// timestamps that are too old or too far in the future. Without validation, this is only noticed once the push request
+// fails, at which point the whole request is lost. The [Client] in this package catches these entries beforehand and,
+// depending on its [Policy], truncates them, drops them, or returns a [ValidationError] without sending anything.
+//
+// The [Limits] should match the limits configured for the Loki instance. [DefaultLimits] returns Loki's defaults.
+package validate
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/internal/labels"
+)
+
+// TruncationMarker is appended to lines and label values that were truncated by [PolicyTruncate]. The marker counts
+// towards the limit, so truncated values are never longer than the limit.
+const TruncationMarker = "...(truncated)"
+
+// Reason is the reason an entry failed validation. The values are the same as the reasons Loki reports in its
+// discarded samples metrics.
+type Reason string
+
+const (
+	// ReasonInvalidLabels means the labels of the entry could not be parsed.
+	ReasonInvalidLabels Reason = "invalid_labels"
+	// ReasonLineTooLong means the line of the entry exceeds [Limits.MaxLineSize].
+	ReasonLineTooLong Reason = "line_too_long"
+	// ReasonMaxLabelNamesPerSeries means the entry has more labels than [Limits.MaxLabelNamesPerSeries].
+	ReasonMaxLabelNamesPerSeries Reason = "max_label_names_per_series"
+	// ReasonLabelNameTooLong means a label name of the entry exceeds [Limits.MaxLabelNameLength].
+	ReasonLabelNameTooLong Reason = "label_name_too_long"
+	// ReasonLabelValueTooLong means a label value of the entry exceeds [Limits.MaxLabelValueLength].
+	ReasonLabelValueTooLong Reason = "label_value_too_long"
+	// ReasonStructuredMetadataTooLarge means the structured metadata of the entry exceeds
+	// [Limits.MaxStructuredMetadataSize].
+	ReasonStructuredMetadataTooLarge Reason = "structured_metadata_too_large"
+	// ReasonGreaterThanMaxSampleAge means the timestamp of the entry is older than [Limits.RejectOldSamplesMaxAge].
+	ReasonGreaterThanMaxSampleAge Reason = "greater_than_max_sample_age"
+	// ReasonTooFarInFuture means the timestamp of the entry is further in the future than
+	// [Limits.CreationGracePeriod].
+	ReasonTooFarInFuture Reason = "too_far_in_future"
+)
+
+// ValidationError is returned when an entry violates the [Limits]. It is distinct from [client.PushStatusError], since
+// the entry was never sent to Loki. It implements the [error] interface.
+type ValidationError struct {
+	// Reason is the limit that was violated.
+	Reason Reason
+	// Labels are the labels of the entry, identifying the stream it belongs to.
+	Labels client.LabelString
+	// Detail describes the violation, such as the size of the line and the limit.
+	Detail string
+}
+
+var _ error = (*ValidationError)(nil)
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("entry for stream %s failed validation (%s): %s", e.Labels, e.Reason, e.Detail)
+}
+
+// Is checks if the target error is a ValidationError. It is used internally by [errors.Is].
+func (e *ValidationError) Is(target error) bool {
+	if target == nil {
+		return false
+	}
+
+	_, ok := target.(*ValidationError)
+
+	return ok
+}
+
+// Limits are the limits entries are validated against. A limit of zero or less disables the corresponding check.
+// Sizes and lengths are measured in bytes.
+type Limits struct {
+	// MaxLineSize is the maximum size of a line, like max_line_size in Loki.
+	MaxLineSize int
+	// MaxLabelNamesPerSeries is the maximum number of labels, like max_label_names_per_series in Loki.
+	MaxLabelNamesPerSeries int
+	// MaxLabelNameLength is the maximum length of a label name, like max_label_name_length in Loki.
+	MaxLabelNameLength int
+	// MaxLabelValueLength is the maximum length of a label value, like max_label_value_length in Loki.
+	MaxLabelValueLength int
+	// MaxStructuredMetadataSize is the maximum total size of the names and values of the structured metadata, like
+	// max_structured_metadata_size in Loki.
+	MaxStructuredMetadataSize int
+	// RejectOldSamplesMaxAge is the maximum age of an entry, like reject_old_samples_max_age in Loki.
+	RejectOldSamplesMaxAge time.Duration
+	// CreationGracePeriod is how far in the future an entry may be, like creation_grace_period in Loki.
+	CreationGracePeriod time.Duration
+}
+
+// DefaultLimits returns the default limits of Loki.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxLineSize:               256 << 10,
+		MaxLabelNamesPerSeries:    15,
+		MaxLabelNameLength:        1024,
+		MaxLabelValueLength:       2048,
+		MaxStructuredMetadataSize: 64 << 10,
+		RejectOldSamplesMaxAge:    7 * 24 * time.Hour,
+		CreationGracePeriod:       10 * time.Minute,
+	}
+}
+
+// Validate checks the entry against the limits at the given time and returns a [ValidationError] for the first
+// violation found, or nil if the entry is valid. Entries with a zero timestamp are sent with the current time, so their
+// timestamp is not checked.
+func (limits Limits) Validate(entry client.Entry, now time.Time) error {
+	entryLabels, err := parseLabels(entry.Labels)
+	if err != nil {
+		return &ValidationError{Reason: ReasonInvalidLabels, Labels: labelString(entry.Labels), Detail: err.Error()}
+	}
+
+	newError := func(reason Reason, format string, args ...any) error {
+		return &ValidationError{Reason: reason, Labels: labelString(entry.Labels), Detail: fmt.Sprintf(format, args...)}
+	}
+
+	if exceeds(len(entry.Line), limits.MaxLineSize) {
+		return newError(ReasonLineTooLong, "line of %d bytes exceeds the limit of %d bytes",
+			len(entry.Line), limits.MaxLineSize)
+	}
+
+	if exceeds(len(entryLabels), limits.MaxLabelNamesPerSeries) {
+		return newError(ReasonMaxLabelNamesPerSeries, "%d labels exceed the limit of %d",
+			len(entryLabels), limits.MaxLabelNamesPerSeries)
+	}
+
+	for name, value := range entryLabels {
+		if exceeds(len(name), limits.MaxLabelNameLength) {
+			return newError(ReasonLabelNameTooLong, "label name %q exceeds the limit of %d bytes",
+				name, limits.MaxLabelNameLength)
+		}
+
+		if exceeds(len(value), limits.MaxLabelValueLength) {
+			return newError(ReasonLabelValueTooLong, "value of label %q with %d bytes exceeds the limit of %d bytes",
+				name, len(value), limits.MaxLabelValueLength)
+		}
+	}
+
+	if size := metadataSize(entry.StructuredMetadata); exceeds(size, limits.MaxStructuredMetadataSize) {
+		return newError(ReasonStructuredMetadataTooLarge, "structured metadata of %d bytes exceeds the limit of %d bytes",
+			size, limits.MaxStructuredMetadataSize)
+	}
+
+	return limits.validateTimestamp(entry.Timestamp, now, newError)
+}
+
+// validateTimestamp checks the timestamp against the age limits, creating errors using newError.
+func (limits Limits) validateTimestamp(
+	timestamp, now time.Time, newError func(reason Reason, format string, args ...any) error,
+) error {
+	if timestamp.IsZero() {
+		return nil
+	}
+
+	if limits.RejectOldSamplesMaxAge > 0 && timestamp.Before(now.Add(-limits.RejectOldSamplesMaxAge)) {
+		return newError(ReasonGreaterThanMaxSampleAge, "timestamp %s is older than %s",
+			timestamp.Format(time.RFC3339), limits.RejectOldSamplesMaxAge)
+	}
+
+	if limits.CreationGracePeriod > 0 && timestamp.After(now.Add(limits.CreationGracePeriod)) {
+		return newError(ReasonTooFarInFuture, "timestamp %s is more than %s in the future",
+			timestamp.Format(time.RFC3339), limits.CreationGracePeriod)
+	}
+
+	return nil
+}
+
+// Truncate returns a copy of the entry with the line and label values truncated to the limits, appending
+// [TruncationMarker] to each truncated value. Other violations cannot be fixed by truncation and are left as is. If the
+// labels cannot be parsed, they are not modified.
+func (limits Limits) Truncate(entry client.Entry) client.Entry {
+	if exceeds(len(entry.Line), limits.MaxLineSize) {
+		entry.Line = truncate(entry.Line, limits.MaxLineSize)
+	}
+
+	if limits.MaxLabelValueLength <= 0 {
+		return entry
+	}
+
+	entryLabels, err := parseLabels(entry.Labels)
+	if err != nil {
+		return entry
+	}
+
+	truncated := false
+
+	for name, value := range entryLabels {
+		if exceeds(len(value), limits.MaxLabelValueLength) {
+			entryLabels[name] = truncate(value, limits.MaxLabelValueLength)
+			truncated = true
+		}
+	}
+
+	if truncated {
+		entry.Labels = client.LabelMap(entryLabels)
+	}
+
+	return entry
+}
+
+// Policy decides what the [Client] does with entries that violate the limits.
+type Policy int
+
+const (
+	// PolicyError returns a [ValidationError] without pushing the entry. It is the default.
+	PolicyError Policy = iota
+	// PolicyTruncate truncates lines and label values that are too long using [Limits.Truncate] and pushes the entry.
+	// Violations that cannot be fixed by truncation result in a [ValidationError], the same as for PolicyError.
+	PolicyTruncate
+	// PolicyDrop silently drops the entry without pushing it. The number of dropped entries is available through
+	// [Client.Dropped].
+	PolicyDrop
+)
+
+// Client is a client that validates entries against the limits before pushing them to the inner client. It implements
+// the [client.Client] interface and is safe to use concurrently as long as the inner client is.
+type Client struct {
+	inner   client.Client
+	limits  Limits
+	policy  Policy
+	dropped *atomic.Uint64
+}
+
+// NewValidateClient creates a new Client wrapping the given client. It defaults to using [DefaultLimits] and
+// [PolicyError].
+func NewValidateClient(inner client.Client) *Client {
+	return &Client{
+		inner:   inner,
+		limits:  DefaultLimits(),
+		policy:  PolicyError,
+		dropped: &atomic.Uint64{},
+	}
+}
+
+// WithLimits returns a new Client with the same inner client and policy and the given limits. The count of dropped
+// entries is not shared between the Clients.
+func (validateClient *Client) WithLimits(limits Limits) *Client {
+	newClient := NewValidateClient(validateClient.inner)
+	newClient.limits = limits
+	newClient.policy = validateClient.policy
+
+	return newClient
+}
+
+// WithPolicy returns a new Client with the same inner client and limits and the given policy. The count of dropped
+// entries is not shared between the Clients.
+func (validateClient *Client) WithPolicy(policy Policy) *Client {
+	newClient := NewValidateClient(validateClient.inner)
+	newClient.limits = validateClient.limits
+	newClient.policy = policy
+
+	return newClient
+}
+
+// Assert that Client implements the [client.Client] and [client.Flusher] interfaces.
+var (
+	_ client.Client  = (*Client)(nil)
+	_ client.Flusher = (*Client)(nil)
+)
+
+// Push implements the [client.Client] interface. The labels and structured metadata carried by the context are added
+// before validating, see [client.MergeContext], since they count towards the limits as well. Valid entries are pushed
+// to the inner client, while invalid entries are handled according to the policy.
+func (validateClient *Client) Push(ctx context.Context, entry client.Entry) error {
+	entry = client.MergeContext(ctx, entry)
+
+	if validateClient.policy == PolicyTruncate {
+		entry = validateClient.limits.Truncate(entry)
+	}
+
+	if err := validateClient.limits.Validate(entry, time.Now()); err != nil {
+		if validateClient.policy == PolicyDrop {
+			validateClient.dropped.Add(1)
+
+			return nil
+		}
+
+		return err
+	}
+
+	return validateClient.inner.Push(ctx, entry)
+}
+
+// Flush implements the [client.Flusher] interface. Since the Client pushes synchronously, it only flushes the inner
+// client if it implements [client.Flusher].
+func (validateClient *Client) Flush(ctx context.Context) error {
+	if flusher, ok := validateClient.inner.(client.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+
+	return nil
+}
+
+// Dropped returns the number of entries dropped by [PolicyDrop] so far.
+func (validateClient *Client) Dropped() uint64 {
+	return validateClient.dropped.Load()
+}
+
+// parseLabels parses the labels of an entry into a map. Nil labels result in an empty map.
+func parseLabels(labeler client.Labeler) (map[string]string, error) {
+	if labeler == nil {
+		return map[string]string{}, nil
+	}
+
+	if labelMap, ok := labeler.(client.LabelMap); ok {
+		return maps.Clone(labelMap), nil
+	}
+
+	return labels.Parse(string(labeler.Label()))
+}
+
+// labelString returns the string representation of the labels, handling nil labels.
+func labelString(labeler client.Labeler) client.LabelString {
+	if labeler == nil {
+		return "{}"
+	}
+
+	return labeler.Label()
+}
+
+// metadataSize returns the size of the structured metadata the same way Loki does, i.e. the sum of the lengths of all
+// names and values.
+func metadataSize(metadata map[string]string) int {
+	size := 0
+
+	for name, value := range metadata {
+		size += len(name) + len(value)
+	}
+
+	return size
+}
+
+// exceeds reports whether the size exceeds the limit, treating a limit of zero or less as no limit.
+func exceeds(size, limit int) bool {
+	return limit > 0 && size > limit
+}
+
+// truncate shortens the value to at most limit bytes including [TruncationMarker], without splitting a UTF-8 encoded
+// rune. If the limit is too small to fit the marker, the value is cut without it.
+func truncate(value string, limit int) string {
+	marker := TruncationMarker
+	if limit <= len(marker) {
+		marker = ""
+	}
+
+	end := limit - len(marker)
+	for end > 0 && !utf8.RuneStart(value[end]) {
+		end--
+	}
+
+	return value[:end] + marker
+}
@@ -0,0 +1,231 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+// testLimits are small limits that are easy to exceed in tests.
+var testLimits = Limits{
+	MaxLineSize:               32,
+	MaxLabelNamesPerSeries:    2,
+	MaxLabelNameLength:        8,
+	MaxLabelValueLength:       24,
+	MaxStructuredMetadataSize: 16,
+	RejectOldSamplesMaxAge:    time.Hour,
+	CreationGracePeriod:       time.Minute,
+}
+
+//nolint:funlen // Most of the function is test cases, no need to worry about length.
+func TestLimits_Validate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	testCases := []struct {
+		name     string
+		entry    client.Entry
+		expected Reason
+	}{
+		{
+			name:  "valid",
+			entry: client.Entry{Timestamp: now, Labels: client.LabelMap{"app": "test"}, Line: "test"},
+		},
+		{
+			name:  "nil-labels-zero-timestamp",
+			entry: client.Entry{Line: "test"},
+		},
+		{
+			name:     "invalid-labels",
+			entry:    client.Entry{Labels: client.LabelString("invalid")},
+			expected: ReasonInvalidLabels,
+		},
+		{
+			name:     "line-too-long",
+			entry:    client.Entry{Line: strings.Repeat("a", 33)},
+			expected: ReasonLineTooLong,
+		},
+		{
+			name:     "too-many-labels",
+			entry:    client.Entry{Labels: client.LabelMap{"a": "1", "b": "2", "c": "3"}},
+			expected: ReasonMaxLabelNamesPerSeries,
+		},
+		{
+			name:     "label-name-too-long",
+			entry:    client.Entry{Labels: client.LabelString(`{application="test"}`)},
+			expected: ReasonLabelNameTooLong,
+		},
+		{
+			name:     "label-value-too-long",
+			entry:    client.Entry{Labels: client.LabelMap{"app": strings.Repeat("a", 25)}},
+			expected: ReasonLabelValueTooLong,
+		},
+		{
+			name:     "structured-metadata-too-large",
+			entry:    client.Entry{StructuredMetadata: map[string]string{"key": strings.Repeat("a", 14)}},
+			expected: ReasonStructuredMetadataTooLarge,
+		},
+		{
+			name:     "too-old",
+			entry:    client.Entry{Timestamp: now.Add(-2 * time.Hour)},
+			expected: ReasonGreaterThanMaxSampleAge,
+		},
+		{
+			name:     "too-far-in-future",
+			entry:    client.Entry{Timestamp: now.Add(2 * time.Minute)},
+			expected: ReasonTooFarInFuture,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := testLimits.Validate(testCase.entry, now)
+			if testCase.expected == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			var validationError *ValidationError
+			require.ErrorAs(t, err, &validationError)
+			require.Equal(t, testCase.expected, validationError.Reason)
+
+			// Disabling all limits should accept every entry with valid labels.
+			if testCase.expected != ReasonInvalidLabels {
+				require.NoError(t, Limits{}.Validate(testCase.entry, now))
+			}
+		})
+	}
+}
+
+func TestLimits_Truncate(t *testing.T) {
+	t.Parallel()
+
+	entry := client.Entry{
+		Labels: client.LabelString(`{app="` + strings.Repeat("a", 30) + `", env="test"}`),
+		Line:   strings.Repeat("ü", 20),
+	}
+
+	truncated := testLimits.Truncate(entry)
+
+	require.Equal(t, strings.Repeat("ü", 9)+TruncationMarker, truncated.Line)
+	require.Equal(t, client.LabelMap{"app": strings.Repeat("a", 10) + TruncationMarker, "env": "test"},
+		truncated.Labels)
+	require.NoError(t, testLimits.Validate(truncated, time.Now()))
+
+	// Values that fit should not be modified.
+	require.Equal(t, truncated, testLimits.Truncate(truncated))
+
+	// Limits too small to fit the marker cut the value without it.
+	require.Equal(t, "aaaa", truncate("aaaaaaaa", 4))
+}
+
+//nolint:funlen // This function is long because it tests multiple cases, so not a code quality issue.
+func TestValidateClient_Push(t *testing.T) {
+	t.Parallel()
+
+	longEntry := client.Entry{Timestamp: time.Now(), Labels: client.LabelMap{"app": "test"}, Line: strings.Repeat("a", 40)}
+
+	testCases := []struct {
+		name     string
+		policy   Policy
+		entry    client.Entry
+		expected *client.Entry
+		dropped  uint64
+		reason   Reason
+	}{
+		{
+			name:   "error",
+			policy: PolicyError,
+			entry:  longEntry,
+			reason: ReasonLineTooLong,
+		},
+		{
+			name:   "truncate",
+			policy: PolicyTruncate,
+			entry:  longEntry,
+			expected: &client.Entry{
+				Labels: client.LabelMap{"app": "test"},
+				Line:   strings.Repeat("a", 18) + TruncationMarker,
+			},
+		},
+		{
+			name:   "truncate-not-fixable",
+			policy: PolicyTruncate,
+			entry:  client.Entry{Timestamp: time.Now().Add(-2 * time.Hour), Line: "test"},
+			reason: ReasonGreaterThanMaxSampleAge,
+		},
+		{
+			name:    "drop",
+			policy:  PolicyDrop,
+			entry:   longEntry,
+			dropped: 1,
+		},
+		{
+			name:     "valid",
+			policy:   PolicyError,
+			entry:    client.Entry{Timestamp: time.Now(), Labels: client.LabelMap{"app": "test"}, Line: "test"},
+			expected: &client.Entry{Labels: client.LabelMap{"app": "test"}, Line: "test"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+			validateClient := NewValidateClient(lokiClient).WithLimits(testLimits).WithPolicy(testCase.policy)
+
+			err := validateClient.Push(t.Context(), testCase.entry)
+			if testCase.reason != "" {
+				var validationError *ValidationError
+				require.ErrorAs(t, err, &validationError)
+				require.Equal(t, testCase.reason, validationError.Reason)
+				require.NotErrorIs(t, err, &client.PushStatusError{})
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, testCase.dropped, validateClient.Dropped())
+
+			streams := fakeServer.Streams()
+
+			if testCase.expected == nil {
+				require.Empty(t, streams, "Expected no entries to be pushed")
+
+				return
+			}
+
+			require.Len(t, streams, 1, "Expected number of streams to match")
+			client.AssertStreamMatchesEntry(t, *testCase.expected, streams[0])
+		})
+	}
+}
+
+func TestValidateClient_With(t *testing.T) {
+	t.Parallel()
+
+	validateClient := NewValidateClient(nil)
+	require.Equal(t, DefaultLimits(), validateClient.limits)
+	require.Equal(t, PolicyError, validateClient.policy)
+
+	configured := validateClient.WithPolicy(PolicyDrop).WithLimits(testLimits)
+	require.Equal(t, testLimits, configured.limits)
+	require.Equal(t, PolicyDrop, configured.policy)
+
+	// The original client should not be modified.
+	require.Equal(t, DefaultLimits(), validateClient.limits)
+	require.Equal(t, PolicyError, validateClient.policy)
+}
@@ -0,0 +1,168 @@
+// Package labels formats and parses the label strings sent to Loki. It is internal so that it can be shared by the
+// client package and the fake server without creating a circular dependency.
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrSyntax is returned when a label string cannot be parsed.
+var ErrSyntax = errors.New("invalid label string")
+
+// Format converts a map of labels to a string that can be added to a stream. It follows the format required by Loki,
+// i.e. `{key="value", key2="value2"}` with the keys sorted alphabetically and the values quoted as Go strings. It does
+// not modify the labels map.
+func Format(labels map[string]string) string {
+	keys := slices.Sorted(maps.Keys(labels))
+
+	return FormatSorted(func(yield func(string, string) bool) {
+		for _, key := range keys {
+			if !yield(key, labels[key]) {
+				return
+			}
+		}
+	})
+}
+
+// FormatSorted formats labels given as pairs of names and values in the same format as [Format]. The pairs must
+// already be sorted by name and are iterated twice, first to compute the size of the result.
+func FormatSorted(pairs iter.Seq2[string, string]) string {
+	// This code is based heavily on the labelsMapToString function in the Promtail client, which is licensed under
+	// the Apache 2.0 license.
+	builder := strings.Builder{}
+	totalSize := 2
+
+	for name, value := range pairs {
+		// add 2 for `, ` between labels and 3 for `=` and quotes around the value
+		totalSize += len(name) + 2 + len(value) + 3
+	}
+
+	builder.Grow(totalSize)
+	builder.WriteByte('{')
+
+	first := true
+
+	for name, value := range pairs {
+		if !first {
+			builder.WriteString(", ")
+		}
+
+		first = false
+
+		builder.WriteString(name)
+		builder.WriteByte('=')
+		builder.WriteString(strconv.Quote(value))
+	}
+
+	builder.WriteByte('}')
+
+	return builder.String()
+}
+
+// Parse converts a label string in the format produced by [Format] back into a map of labels. Whitespace around names,
+// values, and separators is ignored. Values may be quoted using double quotes, single quotes, or backticks, like in
+// Prometheus and LogQL, with the escape sequences of Go strings. If a name appears multiple times, the last value is
+// used.
+func Parse(input string) (map[string]string, error) {
+	rest := strings.TrimSpace(input)
+	if !strings.HasPrefix(rest, "{") || !strings.HasSuffix(rest, "}") {
+		return nil, fmt.Errorf("%w: %q is not enclosed in braces", ErrSyntax, input)
+	}
+
+	rest = strings.TrimSpace(rest[1 : len(rest)-1])
+	labels := make(map[string]string)
+
+	for rest != "" {
+		name, value, remaining, err := parsePair(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w in %q", err, input)
+		}
+
+		labels[name] = value
+		rest = strings.TrimSpace(remaining)
+
+		if rest == "" {
+			break
+		}
+
+		if rest[0] != ',' {
+			return nil, fmt.Errorf("%w: expected ',' in %q", ErrSyntax, input)
+		}
+
+		rest = strings.TrimSpace(rest[1:])
+	}
+
+	return labels, nil
+}
+
+// parsePair parses a single `name="value"` pair from the start of the input, returning the rest of the input.
+func parsePair(input string) (string, string, string, error) {
+	name, rest, found := strings.Cut(input, "=")
+	name = strings.TrimSpace(name)
+
+	if !found || name == "" {
+		return "", "", "", fmt.Errorf("%w: expected label name", ErrSyntax)
+	}
+
+	rest = strings.TrimSpace(rest)
+
+	value, length, err := unquote(rest)
+	if err != nil {
+		return "", "", "", fmt.Errorf("%w for label %q", err, name)
+	}
+
+	return name, value, rest[length:], nil
+}
+
+// unquote unquotes the string at the start of the input, returning its value and the length of the quoted string.
+// Unlike [strconv.Unquote], single quotes may enclose any number of characters, the same as in Prometheus.
+func unquote(input string) (string, int, error) {
+	if input == "" || !strings.ContainsRune("\"'`", rune(input[0])) {
+		return "", 0, fmt.Errorf("%w: expected quoted value", ErrSyntax)
+	}
+
+	quote := input[0]
+	if quote == '`' {
+		end := strings.IndexByte(input[1:], '`')
+		if end < 0 {
+			return "", 0, fmt.Errorf("%w: unterminated raw string", ErrSyntax)
+		}
+
+		return input[1 : end+1], end + 2, nil
+	}
+
+	builder := strings.Builder{}
+	rest := input[1:]
+
+	for rest != "" && rest[0] != quote {
+		if rest[0] == '\n' {
+			return "", 0, fmt.Errorf("%w: newline in quoted value", ErrSyntax)
+		}
+
+		value, multibyte, tail, err := strconv.UnquoteChar(rest, quote)
+		if err != nil {
+			return "", 0, fmt.Errorf("%w: invalid escape sequence in quoted value", ErrSyntax)
+		}
+
+		if value < utf8.RuneSelf || !multibyte {
+			builder.WriteByte(byte(value))
+		} else {
+			builder.WriteRune(value)
+		}
+
+		rest = tail
+	}
+
+	if rest == "" {
+		return "", 0, fmt.Errorf("%w: unterminated quoted value", ErrSyntax)
+	}
+
+	return builder.String(), len(input) - len(rest) + 1, nil
+}
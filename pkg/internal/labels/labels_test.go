@@ -0,0 +1,97 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "{}", Format(nil))
+	require.Equal(t, `{a="1", b="two\n"}`, Format(map[string]string{"b": "two\n", "a": "1"}))
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected map[string]string
+		err      bool
+	}{
+		{
+			name:     "empty",
+			input:    "{}",
+			expected: map[string]string{},
+		},
+		{
+			name:     "formatted",
+			input:    `{a="1", b="two\n"}`,
+			expected: map[string]string{"a": "1", "b": "two\n"},
+		},
+		{
+			name:     "whitespace",
+			input:    ` { a = "1" ,b="2", } `,
+			expected: map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:     "quotes",
+			input:    "{a='it\\'s', b=`raw\\n`, c=\"\\u00e9\\x41\\t\"}",
+			expected: map[string]string{"a": "it's", "b": `raw\n`, "c": "\u00e9A\t"},
+		},
+		{
+			name:  "missing-braces",
+			input: `a="1"`,
+			err:   true,
+		},
+		{
+			name:  "missing-name",
+			input: `{="1"}`,
+			err:   true,
+		},
+		{
+			name:  "unquoted-value",
+			input: `{a=1}`,
+			err:   true,
+		},
+		{
+			name:  "unterminated-value",
+			input: `{a="1}`,
+			err:   true,
+		},
+		{
+			name:  "invalid-escape",
+			input: `{a="\q"}`,
+			err:   true,
+		},
+		{
+			name:  "newline",
+			input: "{a=\"\n\"}",
+			err:   true,
+		},
+		{
+			name:  "missing-comma",
+			input: `{a="1" b="2"}`,
+			err:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			labels, err := Parse(testCase.input)
+			if testCase.err {
+				require.ErrorIs(t, err, ErrSyntax)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, labels)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package labels
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// maxCachedValues is the maximum number of values of the variable label that a [Template] caches. It bounds the memory
+// used by a Template if the values are not limited to a few level names. Further values are formatted on every call.
+const maxCachedValues = 64
+
+// Template formats stream labels consisting of fixed labels and a single variable label, such as the level of a log
+// line. The formatted labels are cached for each value of the variable label, so that formatting them again only costs
+// a lookup. A Template is safe to use concurrently from multiple goroutines.
+type Template struct {
+	fixed map[string]string
+	key   string
+	// static is the formatted fixed labels, used if there is no variable label.
+	static string
+	cache  sync.Map
+	cached atomic.Int32
+}
+
+// NewTemplate creates a new Template with the fixed labels and the key of the variable label. If the key is empty, the
+// Template always formats just the fixed labels. The fixed labels are copied, so the map may be modified afterwards.
+func NewTemplate(fixed map[string]string, key string) *Template {
+	template := &Template{
+		fixed: maps.Clone(fixed),
+		key:   key,
+	}
+
+	if key == "" {
+		template.static = Format(fixed)
+	}
+
+	return template
+}
+
+// Format returns the fixed labels with the variable label set to the value, formatted the same way as by [Format]. The
+// variable label takes precedence over a fixed label with the same key.
+func (template *Template) Format(value string) string {
+	if template.key == "" {
+		return template.static
+	}
+
+	if formatted, ok := template.cache.Load(value); ok {
+		return formatted.(string) //nolint:forcetypeassert // Only strings are stored.
+	}
+
+	withValue := make(map[string]string, len(template.fixed)+1)
+	maps.Copy(withValue, template.fixed)
+	withValue[template.key] = value
+
+	formatted := Format(withValue)
+
+	if template.cached.Add(1) <= maxCachedValues {
+		template.cache.Store(value, formatted)
+	}
+
+	return formatted
+}
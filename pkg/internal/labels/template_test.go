@@ -0,0 +1,28 @@
+package labels
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplate(t *testing.T) {
+	t.Parallel()
+
+	fixed := map[string]string{"app": "test", "level": "fixed"}
+	template := NewTemplate(fixed, "level")
+	fixed["app"] = "modified"
+
+	require.Equal(t, `{app="test", level="info"}`, template.Format("info"))
+	require.Equal(t, `{app="test", level="info"}`, template.Format("info"))
+	require.Equal(t, `{app="test", level="warn"}`, template.Format("warn"))
+
+	// Values beyond the cache limit are still formatted correctly.
+	for i := range maxCachedValues + 2 {
+		require.Equal(t, `{app="test", level="`+strconv.Itoa(i)+`"}`, template.Format(strconv.Itoa(i)))
+	}
+
+	require.Equal(t, `{app="modified", level="fixed"}`, NewTemplate(fixed, "").Format("ignored"))
+	require.Equal(t, "{}", NewTemplate(nil, "").Format("ignored"))
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"io"
 	"log"
+	"log/slog"
 	"maps"
 	"time"
 
@@ -27,9 +28,15 @@ func New(prefix string, flag int, writers ...io.Writer) *log.Logger {
 
 // LokiWriter is a writer that sends log entries to a Loki instance. It implements the [io.Writer] interface. Writes are
 // assumed to always be a full log line.
+//
+// Since [log.Logger] has no notion of levels, every line is sent with the same level, [slog.LevelInfo] unless changed
+// using [LokiWriter.WithLevel]. It is added to the stream labels like in the other adapters, by default as "level" with
+// the value "info". Use [LokiWriter.WithLevelLabel] to change the key and value or to omit the level label.
 type LokiWriter struct {
 	lokiClient         client.Client
 	labels             client.LabelMap
+	level              slog.Level
+	levelLabel         client.LevelLabel
 	preformattedLabels client.LabelString
 }
 
@@ -42,11 +49,15 @@ func NewLokiWriter(lokiClient client.Client, labels map[string]string) *LokiWrit
 		labels = make(map[string]string)
 	}
 
-	return &LokiWriter{
-		lokiClient:         lokiClient,
-		labels:             labels,
-		preformattedLabels: client.LabelMap(labels).Label(),
+	writer := &LokiWriter{
+		lokiClient: lokiClient,
+		labels:     labels,
+		level:      slog.LevelInfo,
+		levelLabel: client.DefaultLevelLabel(),
 	}
+	writer.preformatLabels()
+
+	return writer
 }
 
 // WithLabels returns a new LokiWriter with the labels added. Keys that already exist will be overwritten. Labels may be
@@ -55,7 +66,28 @@ func (writer *LokiWriter) WithLabels(labels map[string]string) *LokiWriter {
 	newWriter := writer.Clone()
 
 	maps.Copy(newWriter.labels, labels)
-	newWriter.preformattedLabels = newWriter.labels.Label()
+	newWriter.preformatLabels()
+
+	return newWriter
+}
+
+// WithLevel returns a new LokiWriter that sends all lines with the given level. It is safe to call concurrently from
+// multiple goroutines.
+func (writer *LokiWriter) WithLevel(level slog.Level) *LokiWriter {
+	newWriter := writer.Clone()
+	newWriter.level = level
+	newWriter.preformatLabels()
+
+	return newWriter
+}
+
+// WithLevelLabel returns a new LokiWriter that adds the level to the stream labels as configured by the given
+// LevelLabel. Use a LevelLabel with an empty key to omit the level label. It is safe to call concurrently from
+// multiple goroutines.
+func (writer *LokiWriter) WithLevelLabel(levelLabel client.LevelLabel) *LokiWriter {
+	newWriter := writer.Clone()
+	newWriter.levelLabel = levelLabel
+	newWriter.preformatLabels()
 
 	return newWriter
 }
@@ -66,10 +98,20 @@ func (writer *LokiWriter) Clone() *LokiWriter {
 	return &LokiWriter{
 		lokiClient:         writer.lokiClient,
 		labels:             maps.Clone(writer.labels),
+		level:              writer.level,
+		levelLabel:         writer.levelLabel,
 		preformattedLabels: writer.preformattedLabels,
 	}
 }
 
+// preformatLabels formats the labels together with the level label and stores the result for use in Write. It modifies
+// the writer in place, so it must only be called on a writer that is not shared yet.
+func (writer *LokiWriter) preformatLabels() {
+	labels := maps.Clone(writer.labels)
+	writer.levelLabel.Apply(labels, writer.level)
+	writer.preformattedLabels = client.LabelMap(labels).Label()
+}
+
 // Write pushes a new log entry to the Loki instance. It first processes the message to remove any trailing newline
 // characters. However, to uphold the requirements of io.Writer, it does not modify the message and returns the original
 // length before processing.
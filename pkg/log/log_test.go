@@ -2,11 +2,12 @@ package log
 
 import (
 	"log"
+	"log/slog"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"github.com/tslnc04/loki-logger/pkg/client"
-	"github.com/tslnc04/loki-logger/pkg/internal/fake"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
 )
 
 const (
@@ -30,7 +31,7 @@ func TestLogging(t *testing.T) {
 			flags:  0,
 			prefix: "",
 			expected: client.Entry{
-				Labels: client.LabelMap(map[string]string{}).Label(),
+				Labels: client.LabelMap{client.DefaultLevelKey: client.LevelNameInfo}.Label(),
 				Line:   defaultMessage,
 			},
 		},
@@ -44,8 +45,9 @@ func TestLogging(t *testing.T) {
 			prefix: "",
 			expected: client.Entry{
 				Labels: client.LabelMap(map[string]string{
-					"key1": "value1",
-					"key2": "value2",
+					"key1":                 "value1",
+					"key2":                 "value2",
+					client.DefaultLevelKey: client.LevelNameInfo,
 				}).Label(),
 				Line: defaultMessage,
 			},
@@ -56,8 +58,8 @@ func TestLogging(t *testing.T) {
 			flags:  log.Lshortfile,
 			prefix: "",
 			expected: client.Entry{
-				Labels: client.LabelMap(map[string]string{}).Label(),
-				Line:   "log_test.go:88: " + defaultMessage,
+				Labels: client.LabelMap{client.DefaultLevelKey: client.LevelNameInfo}.Label(),
+				Line:   "log_test.go:90: " + defaultMessage,
 			},
 		},
 		{
@@ -66,7 +68,7 @@ func TestLogging(t *testing.T) {
 			flags:  0,
 			prefix: "prefix: ",
 			expected: client.Entry{
-				Labels: client.LabelMap(map[string]string{}).Label(),
+				Labels: client.LabelMap{client.DefaultLevelKey: client.LevelNameInfo}.Label(),
 				Line:   "prefix: " + defaultMessage,
 			},
 		},
@@ -76,7 +78,7 @@ func TestLogging(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			t.Parallel()
 
-			fakeServer := fake.NewServer(0)
+			fakeServer := lokitest.NewServer()
 			httpServer := fakeServer.Start()
 
 			defer httpServer.Close()
@@ -88,10 +90,26 @@ func TestLogging(t *testing.T) {
 			logger.Print(defaultMessage)
 
 			streams := fakeServer.Streams()
-			defer fakeServer.Close()
 
 			require.Len(t, streams, 1, "Expected one push stream to be sent to the server")
 			client.AssertStreamMatchesEntry(t, testCase.expected, streams[0])
 		})
 	}
 }
+
+func TestLokiWriter_WithLevel(t *testing.T) {
+	t.Parallel()
+
+	writer := NewLokiWriter(nil, map[string]string{"app": "test"})
+	warnWriter := writer.WithLevel(slog.LevelWarn)
+	detectedWriter := warnWriter.WithLevelLabel(client.LevelLabel{Key: "detected_level"})
+	omittedWriter := warnWriter.WithLevelLabel(client.LevelLabel{})
+
+	require.Equal(t, client.LabelString(`{app="test", level="info"}`), writer.preformattedLabels)
+	require.Equal(t, client.LabelString(`{app="test", level="warn"}`), warnWriter.preformattedLabels)
+	require.Equal(t, client.LabelString(`{app="test", detected_level="warn"}`), detectedWriter.preformattedLabels)
+	require.Equal(t, client.LabelString(`{app="test"}`), omittedWriter.preformattedLabels)
+
+	// Ensure the level label is not added to the static labels.
+	require.Equal(t, client.LabelMap{"app": "test"}, warnWriter.labels)
+}
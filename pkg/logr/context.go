@@ -0,0 +1,56 @@
+package logr
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+)
+
+// WithContext returns a new LokiSink that pushes entries logged using Info and Error with the given context. This way,
+// labels and structured metadata added to the context using [client.ContextWithLabels] and
+// [client.ContextWithMetadata] are added to the entries by the client. Entries logged through the slog interop use the
+// context of the record instead. It is safe to call concurrently from multiple goroutines.
+func (sink *LokiSink) WithContext(ctx context.Context) *LokiSink {
+	newSink := sink.Clone()
+	newSink.ctx = ctx
+
+	return newSink
+}
+
+// context returns the context to push entries from Info and Error with.
+func (sink *LokiSink) context() context.Context {
+	if sink.ctx == nil {
+		return context.Background()
+	}
+
+	return sink.ctx
+}
+
+// FromContext returns the logger stored in the context using [logr.NewContext], like [logr.FromContext]. If the
+// logger uses a [LokiSink], the returned logger pushes its entries with the given context, see [LokiSink.WithContext].
+// This allows request handlers to retrieve a logger that includes the labels and metadata of the request.
+func FromContext(ctx context.Context) (logr.Logger, error) {
+	logger, err := logr.FromContext(ctx)
+	if err != nil {
+		return logger, err
+	}
+
+	return WithContext(ctx, logger), nil
+}
+
+// FromContextOrDiscard is like [FromContext], but returns a logger that discards all entries if the context does not
+// contain a logger, like [logr.FromContextOrDiscard].
+func FromContextOrDiscard(ctx context.Context) logr.Logger {
+	return WithContext(ctx, logr.FromContextOrDiscard(ctx))
+}
+
+// WithContext returns a copy of the logger that pushes its entries with the given context if it uses a [LokiSink], see
+// [LokiSink.WithContext]. Other loggers are returned unchanged.
+func WithContext(ctx context.Context, logger logr.Logger) logr.Logger {
+	sink, ok := logger.GetSink().(*LokiSink)
+	if !ok {
+		return logger
+	}
+
+	return logger.WithSink(sink.WithContext(ctx))
+}
@@ -0,0 +1,51 @@
+package logr
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.SetStrict(true)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	_, err := FromContext(t.Context())
+	require.Error(t, err)
+
+	logger := New(client.NewLokiClient(httpServer.URL+client.PushPath), 0)
+	ctx := logr.NewContext(t.Context(), *logger)
+	ctx = client.ContextWithLabels(ctx, "tenant", "a")
+	ctx = client.ContextWithMetadata(ctx, "request_id", "1")
+
+	contextLogger, err := FromContext(ctx)
+	require.NoError(t, err)
+
+	contextLogger.Info(defaultMessage)
+	FromContextOrDiscard(ctx).Error(nil, defaultMessage)
+	logger.Info(defaultMessage)
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 3, "Expected number of streams to match")
+	require.Equal(t, `{level="info", tenant="a"}`, streams[0].Labels)
+	require.Equal(t, `{level="error", tenant="a"}`, streams[1].Labels)
+	require.Equal(t, `{level="info"}`, streams[2].Labels, "Expected the original logger to not be modified")
+	require.Contains(t, streams[0].Entries[0].StructuredMetadata, push.LabelAdapter{Name: "request_id", Value: "1"})
+}
+
+func TestWithContext_OtherSink(t *testing.T) {
+	t.Parallel()
+
+	logger := logr.Discard()
+	require.Equal(t, logger, WithContext(t.Context(), logger))
+}
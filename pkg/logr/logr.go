@@ -1,26 +1,35 @@
 // Package logr provides a logr.LogSink implementation that sends log entries to a Loki instance.
+//
+// The sink also implements logr.SlogSink, so a logger converted using logr.ToSlogHandler keeps slog groups, levels, and
+// source information, producing the same entries as the slog adapter.
 package logr
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"maps"
 	"runtime"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/client/sample"
+	"github.com/tslnc04/loki-logger/pkg/internal/labels"
 )
 
 const (
 	// ErrorKey is the key added to the structured metadata when an error is logged. Its value is the stringified
 	// error.
 	ErrorKey = "error"
-	// LevelKey is the key added to the stream labels for a log line. For errors, it will be -1.
-	LevelKey = "level"
-	// NameKey is the key added to the stream labels for a log line. Its value is the names of the
-	// logger joined by "/".
+	// LevelKey is the key added to the stream labels for a log line unless configured otherwise using
+	// [LokiSink.WithLevelLabel]. Its value is the name of the level from [client.DefaultLevelMapper], where V(n) is
+	// mapped to slog.Level(-n) and errors to [slog.LevelError], e.g. "info" for V(0) and "debug" for V(1).
+	LevelKey = client.DefaultLevelKey
+	// NameKey is the key added to the stream labels for a log line if the logger has a name. Its value is the names of
+	// the logger joined by [DefaultNameSeparator]. See [LokiSink.WithNamePlacement] and [LokiSink.WithNameSeparator] to
+	// change where the name is added and how the names are joined.
 	NameKey = "name"
 	// SourceKey is the prefix for the keys added to the structured metadata when a log line is logged. The actual
 	// keys used are SourceKey+"_function", SourceKey+"_file", and SourceKey+"_line".
@@ -46,17 +55,51 @@ func New(lokiClient client.Client, level ...int) *logr.Logger {
 // LokiSink is a [logr.LogSink] that sends log entries to a Loki instance. Any keys and values added to the
 // [logr.Logger] (and thus this sink) will be added as stream labels. Any keys and values set when calling a logging
 // function will be added as structured metadata.
+//
+// Values are rendered similar to funcr. Values implementing [logr.Marshaler] are replaced by the result of MarshalLog,
+// errors and [fmt.Stringer] values use their Error and String methods, and structs, maps, and slices are rendered as
+// JSON. A key without a value gets the value [NoValue].
+//
+// A [sample.Sampler] can be set using [LokiSink.WithSampler]. Samplers see the verbosity V(n) as slog.Level(-n) and
+// errors as [slog.LevelError]. The same levels are used for the level label, see [LokiSink.WithLevelLabel].
+//
+// The verbosity of the sink is fixed when created, unless a [slog.Leveler] such as [client.AtomicLevel] is set using
+// [LokiSink.WithLeveler]. The leveler is consulted on every call to Enabled, so the verbosity can be changed at runtime
+// without creating a new logger.
+//
+// The stream labels, including the name if placed in the labels, are formatted once when the sink is created and cached
+// for each level, so that logging a line does not format them again.
 type LokiSink struct {
 	lokiClient client.Client
 	info       logr.RuntimeInfo
 	callDepth  int
 	level      int
+	// leveler overrides level if set. V(n) is enabled if slog.Level(-n) is at least the level of the leveler.
+	leveler slog.Leveler
 	// labels is a map of labels to add to each log entry. It should never be nil.
 	labels map[string]string
+	// groups is the list of groups added using WithGroup. It only applies to slog attributes, see [LokiSink.Handle].
+	groups []string
+	// names is the list of names added using WithName. They are joined by nameSeparator and placed according to
+	// namePlacement when creating an entry.
+	names         []string
+	nameSeparator string
+	namePlacement NamePlacement
+	sampler       sample.Sampler
+	levelLabel    client.LevelLabel
+	// ctx is the context passed to the client when pushing entries from Info and Error. It is nil unless set using
+	// WithContext, in which case context.Background is used.
+	ctx context.Context
+	// labelTemplate caches the formatted labels for each level. It must be recreated whenever the labels, the names,
+	// or the level label change.
+	labelTemplate *labels.Template
 }
 
-// Assert that LokiSink implements the [logr.LogSink] interface.
-var _ logr.LogSink = (*LokiSink)(nil)
+// Assert that LokiSink implements the [logr.LogSink] and [logr.SlogSink] interfaces.
+var (
+	_ logr.LogSink  = (*LokiSink)(nil)
+	_ logr.SlogSink = (*LokiSink)(nil)
+)
 
 // NewLokiSink creates a new LokiSink with the given client. Optionally, it can be configured with the given level. If
 // multiple levels are provided, the sink will log only messages less than or equal to the first level provided. It is
@@ -67,17 +110,55 @@ func NewLokiSink(lokiClient client.Client, level ...int) *LokiSink {
 		logLevel = level[0]
 	}
 
-	return &LokiSink{
-		lokiClient: lokiClient,
-		labels:     make(map[string]string),
-		level:      logLevel,
+	sink := &LokiSink{
+		lokiClient:    lokiClient,
+		labels:        make(map[string]string),
+		level:         logLevel,
+		nameSeparator: DefaultNameSeparator,
+		levelLabel:    client.DefaultLevelLabel(),
 	}
+	sink.preformatLabels()
+
+	return sink
 }
 
-// WithLevel returns a new LokiSink with the given level. It is safe to call concurrently from multiple goroutines.
+// WithLevel returns a new LokiSink with the given level. It replaces any leveler set using [LokiSink.WithLeveler]. It
+// is safe to call concurrently from multiple goroutines.
 func (sink *LokiSink) WithLevel(level int) *LokiSink {
 	newSink := sink.Clone()
 	newSink.level = level
+	newSink.leveler = nil
+
+	return newSink
+}
+
+// WithLeveler returns a new LokiSink whose verbosity is determined by the given leveler, such as a [client.AtomicLevel]
+// or [slog.LevelVar]. V(n) is enabled if slog.Level(-n) is at least the level of the leveler, so a level of -4 enables
+// V(4) and below. The leveler is shared with the original sink. A nil leveler restores the level set using
+// [LokiSink.WithLevel]. It is safe to call concurrently from multiple goroutines.
+func (sink *LokiSink) WithLeveler(leveler slog.Leveler) *LokiSink {
+	newSink := sink.Clone()
+	newSink.leveler = leveler
+
+	return newSink
+}
+
+// WithSampler returns a new LokiSink that uses the given sampler to decide which log lines are pushed to Loki. A nil
+// sampler disables sampling. It is safe to call concurrently from multiple goroutines.
+func (sink *LokiSink) WithSampler(sampler sample.Sampler) *LokiSink {
+	newSink := sink.Clone()
+	newSink.sampler = sampler
+
+	return newSink
+}
+
+// WithLevelLabel returns a new LokiSink that adds the level to the stream labels as configured by the given
+// LevelLabel. Use a LevelLabel with an empty key to omit the level label. It is safe to call concurrently from multiple
+// goroutines.
+func (sink *LokiSink) WithLevelLabel(levelLabel client.LevelLabel) *LokiSink {
+	newSink := sink.Clone()
+	newSink.levelLabel = levelLabel
+	newSink.preformatLabels()
 
 	return newSink
 }
@@ -86,74 +167,104 @@ func (sink *LokiSink) WithLevel(level int) *LokiSink {
 // goroutines.
 func (sink *LokiSink) Clone() *LokiSink {
 	newSink := &LokiSink{
-		lokiClient: sink.lokiClient,
-		info:       sink.info,
-		callDepth:  sink.callDepth,
-		level:      sink.level,
-		labels:     maps.Clone(sink.labels),
+		lokiClient:    sink.lokiClient,
+		info:          sink.info,
+		callDepth:     sink.callDepth,
+		level:         sink.level,
+		leveler:       sink.leveler,
+		labels:        maps.Clone(sink.labels),
+		groups:        slices.Clone(sink.groups),
+		names:         slices.Clone(sink.names),
+		nameSeparator: sink.nameSeparator,
+		namePlacement: sink.namePlacement,
+		sampler:       sink.sampler,
+		levelLabel:    sink.levelLabel,
+		ctx:           sink.ctx,
+		labelTemplate: sink.labelTemplate,
 	}
 
 	return newSink
 }
 
+// preformatLabels creates the template used to format the labels together with the name and the level label. It
+// modifies the sink in place, so it must only be called on a sink that is not shared yet.
+func (sink *LokiSink) preformatLabels() {
+	fixed := sink.labels
+	if sink.namePlacement == NameInLabels && len(sink.names) > 0 {
+		fixed = maps.Clone(sink.labels)
+		fixed[NameKey] = sink.name()
+	}
+
+	sink.labelTemplate = labels.NewTemplate(fixed, sink.levelLabel.Key)
+}
+
+// entryLabels returns the stream labels for a log line with the given level.
+func (sink *LokiSink) entryLabels(level slog.Level) client.LabelString {
+	return client.LabelString(sink.labelTemplate.Format(sink.levelLabel.Name(level)))
+}
+
 // Init allows the sink to be initialized with the given [logr.RuntimeInfo]. It modifies the sink in place.
 func (sink *LokiSink) Init(info logr.RuntimeInfo) {
 	sink.info = info
 }
 
 // Enabled reports whether the sink is enabled for the given level, i.e. whether the provided level is less than or
-// equal to the sink's level. It is safe to call concurrently from multiple goroutines.
+// equal to the sink's level. If a leveler is set, its current level is used instead. It is safe to call concurrently
+// from multiple goroutines.
 func (sink *LokiSink) Enabled(level int) bool {
+	if sink.leveler != nil {
+		return slog.Level(-level) >= sink.leveler.Level()
+	}
+
 	return level <= sink.level
 }
 
 // Info logs the message with the provided level. It adds the level to the stream labels and the keys and values to the
 // structured metadata. It is safe to call concurrently from multiple goroutines.
 func (sink *LokiSink) Info(level int, msg string, keysAndValues ...any) {
-	entry := sink.createEntry(level, msg, keysAndValues)
-	_ = sink.lokiClient.Push(context.Background(), entry)
+	entry := sink.createEntry(slog.Level(-level), msg, keysAndValues)
+	_ = sink.push(sink.context(), slog.Level(-level), entry)
 }
 
-// Error logs the message with the provided error and level. It adds the level set to -1 to the stream labels and the
-// keys and values to the structured metadata. It is safe to call concurrently from multiple goroutines.
+// Error logs the message with the provided error. It adds the error level to the stream labels and the keys and values
+// to the structured metadata. It is safe to call concurrently from multiple goroutines.
 func (sink *LokiSink) Error(err error, msg string, keysAndValues ...any) {
-	keysAndValues = append(keysAndValues, ErrorKey, err)
-	entry := sink.createEntry(-1, msg, keysAndValues)
-	_ = sink.lokiClient.Push(context.Background(), entry)
+	entry := sink.createEntry(slog.LevelError, msg, keysAndValues)
+	entry.StructuredMetadata[ErrorKey] = formatValue(err)
+	_ = sink.push(sink.context(), slog.LevelError, entry)
 }
 
-// WithValues returns a new LokiSink with the given keys and values added to the stream labels. If there are an odd
-// number of keys and values, the last value is ignored. It is safe to call concurrently from multiple goroutines.
+// push sends the entry to Loki unless it is rejected by the sampler. The level is only used for sampling.
+func (sink *LokiSink) push(ctx context.Context, level slog.Level, entry client.Entry) error {
+	if sink.sampler != nil && !sink.sampler.Sample(level, entry) {
+		return nil
+	}
+
+	return sink.lokiClient.Push(ctx, entry)
+}
+
+// WithValues returns a new LokiSink with the given keys and values added to the stream labels. Values are rendered like
+// structured metadata, see [LokiSink]. If there are an odd number of keys and values, the last key is added with the
+// value [NoValue]. It is safe to call concurrently from multiple goroutines.
 //
 //nolint:ireturn
 func (sink *LokiSink) WithValues(keysAndValues ...any) logr.LogSink {
 	newSink := sink.Clone()
-
-	if len(keysAndValues)%2 != 0 {
-		keysAndValues = keysAndValues[:len(keysAndValues)-1]
-	}
-
-	for i := 0; i < len(keysAndValues); i += 2 {
-		key := fmt.Sprint(keysAndValues[i])
-		value := fmt.Sprint(keysAndValues[i+1])
-		newSink.labels[key] = value
-	}
+	addValues(newSink.labels, keysAndValues)
+	newSink.preformatLabels()
 
 	return newSink
 }
 
-// WithName returns a new LokiSink with the given name joined to the existing names in the stream labels by a `/`. It is
+// WithName returns a new LokiSink with the given name appended to the existing names. By default, the names are joined
+// by a `/` and added to the stream labels, see [LokiSink.WithNamePlacement] and [LokiSink.WithNameSeparator]. It is
 // safe to call concurrently from multiple goroutines.
 //
 //nolint:ireturn
 func (sink *LokiSink) WithName(name string) logr.LogSink {
 	newSink := sink.Clone()
-
-	if names, ok := newSink.labels[NameKey]; ok {
-		newSink.labels[NameKey] = fmt.Sprintf("%s/%s", names, name)
-	} else {
-		newSink.labels[NameKey] = name
-	}
+	newSink.names = append(newSink.names, name)
+	newSink.preformatLabels()
 
 	return newSink
 }
@@ -175,14 +286,9 @@ func (sink *LokiSink) WithCallDepth(depth int) logr.LogSink {
 // createEntry creates a new [client.Entry] with the given level, message, and keys and values. It adds the level to the
 // stream labels and the keys and values to the structured metadata. It also adds the source keys to the structured
 // metadata. It is safe to call concurrently from multiple goroutines.
-func (sink *LokiSink) createEntry(level int, msg string, keysAndValues []any) client.Entry {
-	labels := maps.Clone(sink.labels)
-	labels[LevelKey] = strconv.Itoa(level)
-
-	metadata := make(map[string]string)
-	if len(keysAndValues) > 1 {
-		addValuesToLabels(metadata, keysAndValues)
-	}
+func (sink *LokiSink) createEntry(level slog.Level, msg string, keysAndValues []any) client.Entry {
+	metadata := make(map[string]string, len(keysAndValues)/2)
+	addValues(metadata, keysAndValues)
 
 	callDepth := sink.callDepth
 	if callDepth == 0 {
@@ -197,10 +303,12 @@ func (sink *LokiSink) createEntry(level int, msg string, keysAndValues []any) cl
 		source.addToLabels(metadata)
 	}
 
+	line := sink.addName(metadata, msg)
+
 	entry := client.Entry{
 		Timestamp:          time.Now(),
-		Labels:             client.LabelMap(labels).Label(),
-		Line:               msg,
+		Labels:             sink.entryLabels(level),
+		Line:               line,
 		StructuredMetadata: metadata,
 	}
 
@@ -223,14 +331,7 @@ func newSource(skip int) *source {
 		return nil
 	}
 
-	frames := runtime.CallersFrames([]uintptr{pc})
-	frame, _ := frames.Next()
-
-	return &source{
-		function: frame.Function,
-		file:     frame.File,
-		line:     frame.Line,
-	}
+	return sourceFromPC(pc)
 }
 
 // addToLabels adds the source to the labels, ignoring any values that are empty. It will modify the labels in place.
@@ -247,17 +348,3 @@ func (source *source) addToLabels(labels map[string]string) {
 		labels[SourceKey+"_line"] = strconv.Itoa(source.line)
 	}
 }
-
-// addValuesToLabels modifies the labels in place by adding the keys and values. If there are an odd number of keys and
-// values, the last value is ignored. It uses fmt.Sprint to convert the keys and values to strings.
-func addValuesToLabels(labels map[string]string, keysAndValues []any) {
-	if len(keysAndValues)%2 != 0 {
-		keysAndValues = keysAndValues[:len(keysAndValues)-1]
-	}
-
-	for i := 0; i < len(keysAndValues); i += 2 {
-		key := fmt.Sprint(keysAndValues[i])
-		value := fmt.Sprint(keysAndValues[i+1])
-		labels[key] = value
-	}
-}
@@ -1,13 +1,17 @@
 package logr
 
 import (
+	"context"
+	"log/slog"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/require"
 	"github.com/tslnc04/loki-logger/pkg/client"
-	"github.com/tslnc04/loki-logger/pkg/internal/fake"
+	"github.com/tslnc04/loki-logger/pkg/client/sample"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
 )
 
 const (
@@ -36,13 +40,13 @@ func TestInfoVerbosityLevels(t *testing.T) {
 			level: 0,
 			expected: []client.Entry{{
 				Labels: client.LabelMap{
-					LevelKey: "0",
+					LevelKey: client.LevelNameInfo,
 				},
 				Line: defaultMessage,
 				StructuredMetadata: map[string]string{
 					SourceKey + "_function": currentPackage + ".TestInfoVerbosityLevels.func1",
 					SourceKey + "_file":     currentFile,
-					SourceKey + "_line":     "63",
+					SourceKey + "_line":     "68",
 				},
 			}},
 		},
@@ -52,7 +56,8 @@ func TestInfoVerbosityLevels(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			t.Parallel()
 
-			fakeServer := fake.NewServer(0)
+			fakeServer := lokitest.NewServer()
+			fakeServer.SetStrict(true)
 			httpServer := fakeServer.Start()
 
 			defer httpServer.Close()
@@ -63,7 +68,6 @@ func TestInfoVerbosityLevels(t *testing.T) {
 			logger.V(testCase.level).Info(defaultMessage)
 
 			streams := fakeServer.Streams()
-			defer fakeServer.Close()
 
 			require.Len(t, streams, len(testCase.expected), "Expected number of streams to match")
 
@@ -79,14 +83,14 @@ func TestErrorVerbosityLevels(t *testing.T) {
 
 	expectedEntry := client.Entry{
 		Labels: client.LabelMap{
-			LevelKey: "-1",
+			LevelKey: client.LevelNameError,
 		},
 		Line: defaultMessage,
 		StructuredMetadata: map[string]string{
 			ErrorKey:                "<nil>",
 			SourceKey + "_function": currentPackage + ".TestErrorVerbosityLevels.func1",
 			SourceKey + "_file":     currentFile,
-			SourceKey + "_line":     "122",
+			SourceKey + "_line":     "127",
 		},
 	}
 
@@ -111,7 +115,8 @@ func TestErrorVerbosityLevels(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			t.Parallel()
 
-			fakeServer := fake.NewServer(0)
+			fakeServer := lokitest.NewServer()
+			fakeServer.SetStrict(true)
 			httpServer := fakeServer.Start()
 
 			defer httpServer.Close()
@@ -122,7 +127,6 @@ func TestErrorVerbosityLevels(t *testing.T) {
 			logger.V(testCase.level).Error(nil, defaultMessage)
 
 			streams := fakeServer.Streams()
-			defer fakeServer.Close()
 
 			require.Len(t, streams, len(testCase.expected), "Expected number of streams to match")
 
@@ -186,6 +190,28 @@ func TestLokiSink_WithLevel(t *testing.T) {
 	require.Equal(t, 0, lokiSink.level)
 }
 
+func TestLokiSink_WithLeveler(t *testing.T) {
+	t.Parallel()
+
+	atomicLevel := client.NewAtomicLevel(slog.LevelInfo)
+	lokiSink := NewLokiSink(nil, maxLevel)
+	leveledSink := lokiSink.WithLeveler(atomicLevel)
+
+	require.Nil(t, lokiSink.leveler, "Expected the original sink to not be modified")
+	require.True(t, leveledSink.Enabled(0))
+	require.False(t, leveledSink.Enabled(1))
+
+	atomicLevel.SetVerbosity(4)
+	require.True(t, leveledSink.Enabled(4))
+	require.False(t, leveledSink.Enabled(5))
+	require.True(t, leveledSink.Clone().Enabled(4), "Expected clones to share the leveler")
+
+	atomicLevel.SetLevel(slog.LevelWarn)
+	require.False(t, leveledSink.Enabled(0))
+
+	require.True(t, leveledSink.WithLevel(maxLevel).Enabled(maxLevel), "Expected WithLevel to replace the leveler")
+}
+
 func TestLokiSink_Clone(t *testing.T) {
 	t.Parallel()
 
@@ -256,20 +282,19 @@ func TestLokiSink_WithName(t *testing.T) {
 	modifiedLokiSink, ok := modifiedSink.(*LokiSink)
 	require.True(t, ok)
 
-	require.Equal(t, "test", modifiedLokiSink.labels[NameKey])
+	require.Equal(t, "test", modifiedLokiSink.name())
 
 	// Ensure the original sink is not modified.
-	require.Empty(t, lokiSink.labels)
+	require.Empty(t, lokiSink.name())
 
 	twiceModifiedSink := modifiedSink.WithName("test2")
 	twiceModifiedLokiSink, ok := twiceModifiedSink.(*LokiSink)
 	require.True(t, ok)
-	require.Equal(t, "test/test2", twiceModifiedLokiSink.labels[NameKey])
+	require.Equal(t, "test/test2", twiceModifiedLokiSink.name())
 
 	// Ensure the original sinks are not modified.
-	require.Empty(t, lokiSink.labels)
-	require.Len(t, modifiedLokiSink.labels, 1)
-	require.Equal(t, "test", modifiedLokiSink.labels[NameKey])
+	require.Empty(t, lokiSink.name())
+	require.Equal(t, "test", modifiedLokiSink.name())
 }
 
 func TestLokiSink_WithCallDepth(t *testing.T) {
@@ -287,3 +312,89 @@ func TestLokiSink_WithCallDepth(t *testing.T) {
 	// Ensure the original sink is not modified.
 	require.Equal(t, 0, lokiSink.callDepth)
 }
+
+func TestLokiSink_WithSampler(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.SetStrict(true)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	filter := sample.NewFilter(sample.NewCountSampler(time.Minute, 1, 0), true)
+	lokiSink := NewLokiSink(lokiClient, 0)
+	sampledSink := lokiSink.WithSampler(filter)
+
+	require.Nil(t, lokiSink.sampler, "Expected the original sink to not be modified")
+
+	logger := logr.New(sampledSink)
+	for range 3 {
+		logger.Info(defaultMessage)
+		logger.Error(nil, defaultMessage)
+	}
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 4, "Expected the first info line and all errors to be sent")
+	require.Equal(t, uint64(4), filter.Kept())
+	require.Equal(t, uint64(2), filter.Dropped())
+}
+
+func TestLokiSink_WithLevelLabel(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.SetStrict(true)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	lokiSink := NewLokiSink(lokiClient, maxLevel)
+	customSink := lokiSink.WithLevelLabel(client.LevelLabel{Key: "detected_level"})
+
+	require.Equal(t, client.DefaultLevelLabel(), lokiSink.levelLabel, "Expected the original sink to not be modified")
+
+	logr.New(lokiSink).V(1).Info(defaultMessage)
+	logr.New(lokiSink).V(maxLevel).Info(defaultMessage)
+	logr.New(customSink).Error(nil, defaultMessage)
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 3, "Expected number of streams to match")
+	require.Equal(t, `{level="debug"}`, streams[0].Labels)
+	require.Equal(t, `{level="trace"}`, streams[1].Labels)
+	require.Equal(t, `{detected_level="error"}`, streams[2].Labels)
+}
+
+// discardClient is a client that discards all entries, used to measure the cost of creating them.
+type discardClient struct{}
+
+func (discardClient) Push(context.Context, client.Entry) error {
+	return nil
+}
+
+func BenchmarkLokiSink_Info(b *testing.B) {
+	sink := NewLokiSink(discardClient{}).WithValues("app", "bench", "env", "test").WithName("controller")
+	logger := logr.New(sink)
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		logger.Info(defaultMessage, "count", 1)
+	}
+}
+
+func BenchmarkLokiSink_Handle(b *testing.B) {
+	sink := NewLokiSink(discardClient{}).WithAttrs([]slog.Attr{slog.String("app", "bench"), slog.String("env", "test")})
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, defaultMessage, 0)
+	record.AddAttrs(slog.Int("count", 1))
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		_ = sink.Handle(b.Context(), record)
+	}
+}
@@ -0,0 +1,66 @@
+package logr
+
+import "strings"
+
+// DefaultNameSeparator is the separator used to join the names of a logger unless configured otherwise using
+// [LokiSink.WithNameSeparator].
+const DefaultNameSeparator = "/"
+
+// NamePlacement determines where the name of a logger, as set using WithName, is added to its log entries.
+type NamePlacement int
+
+const (
+	// NameInLabels adds the name to the stream labels using [NameKey]. Every distinct name creates a separate stream.
+	// It is the default.
+	NameInLabels NamePlacement = iota
+	// NameInMetadata adds the name to the structured metadata using [NameKey], keeping the number of streams low.
+	NameInMetadata
+	// NameInLine prefixes the line with the name followed by a colon and a space, e.g. "controller/pod: message".
+	NameInLine
+)
+
+// WithNamePlacement returns a new LokiSink that adds the name of the logger to its log entries as determined by the
+// placement. It applies to names added both before and after the call. It is safe to call concurrently from multiple
+// goroutines.
+func (sink *LokiSink) WithNamePlacement(placement NamePlacement) *LokiSink {
+	newSink := sink.Clone()
+	newSink.namePlacement = placement
+	newSink.preformatLabels()
+
+	return newSink
+}
+
+// WithNameSeparator returns a new LokiSink that joins the names of the logger using the given separator instead of
+// [DefaultNameSeparator]. It applies to names added both before and after the call. It is safe to call concurrently
+// from multiple goroutines.
+func (sink *LokiSink) WithNameSeparator(separator string) *LokiSink {
+	newSink := sink.Clone()
+	newSink.nameSeparator = separator
+	newSink.preformatLabels()
+
+	return newSink
+}
+
+// name returns the names of the logger joined by the separator. It is empty if no name was set.
+func (sink *LokiSink) name() string {
+	return strings.Join(sink.names, sink.nameSeparator)
+}
+
+// addName adds the name of the logger to the metadata or line, depending on the placement. It modifies the metadata in
+// place and returns the line, which is only changed if the name is placed in the line. Names placed in the labels are
+// already part of the label template, see preformatLabels.
+func (sink *LokiSink) addName(metadata map[string]string, line string) string {
+	if len(sink.names) == 0 {
+		return line
+	}
+
+	switch sink.namePlacement {
+	case NameInLabels:
+	case NameInMetadata:
+		metadata[NameKey] = sink.name()
+	case NameInLine:
+		return sink.name() + ": " + line
+	}
+
+	return line
+}
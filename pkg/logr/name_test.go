@@ -0,0 +1,82 @@
+package logr
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+//nolint:funlen // This function is long because it tests multiple cases, so not a code quality issue.
+func TestLokiSink_WithNamePlacement(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		configure        func(sink *LokiSink) *LokiSink
+		expectedLabels   string
+		expectedLine     string
+		expectedMetadata string
+	}{
+		{
+			name:           "labels",
+			configure:      func(sink *LokiSink) *LokiSink { return sink },
+			expectedLabels: `{level="info", name="controller/pod"}`,
+			expectedLine:   defaultMessage,
+		},
+		{
+			name: "metadata",
+			configure: func(sink *LokiSink) *LokiSink {
+				return sink.WithNamePlacement(NameInMetadata)
+			},
+			expectedLabels:   `{level="info"}`,
+			expectedLine:     defaultMessage,
+			expectedMetadata: "controller/pod",
+		},
+		{
+			name: "line-with-separator",
+			configure: func(sink *LokiSink) *LokiSink {
+				return sink.WithNamePlacement(NameInLine).WithNameSeparator(".")
+			},
+			expectedLabels: `{level="info"}`,
+			expectedLine:   "controller.pod: " + defaultMessage,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			fakeServer.SetStrict(true)
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			lokiSink := testCase.configure(NewLokiSink(client.NewLokiClient(httpServer.URL+client.PushPath), 0))
+			logger := logr.New(lokiSink).WithName("controller").WithName("pod")
+
+			logger.Info(defaultMessage)
+			slog.New(logr.ToSlogHandler(logger)).Info(defaultMessage)
+
+			streams := fakeServer.Streams()
+
+			require.Len(t, streams, 2, "Expected number of streams to match")
+
+			for _, stream := range streams {
+				require.Equal(t, testCase.expectedLabels, stream.Labels)
+				require.Equal(t, testCase.expectedLine, stream.Entries[0].Line)
+
+				metadata := map[string]string{}
+				for _, label := range stream.Entries[0].StructuredMetadata {
+					metadata[label.Name] = label.Value
+				}
+
+				require.Equal(t, testCase.expectedMetadata, metadata[NameKey])
+			}
+		})
+	}
+}
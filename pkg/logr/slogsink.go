@@ -0,0 +1,125 @@
+package logr
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/tslnc04/loki-logger/pkg/client"
+)
+
+// Handle converts the slog record to a [client.Entry] and pushes it to Loki. It implements the [logr.SlogSink]
+// interface, which is used by [logr.ToSlogHandler] instead of the lossy conversion to keys and values.
+//
+// The record is converted the same way as by the slog adapter: the level is added to the stream labels, the
+// attributes of the record to the structured metadata with any groups joined by an underscore (`_`), and the source, if
+// available, to the structured metadata using [SourceKey]. Since the level of a record is already on the slog scale,
+// it is used for the level label and the sampler as is. It is safe to call concurrently from multiple goroutines.
+func (sink *LokiSink) Handle(ctx context.Context, record slog.Record) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	metadata := make(map[string]string, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		addAttr(metadata, sink.groups, attr)
+
+		return true
+	})
+
+	if record.PC != 0 {
+		sourceFromPC(record.PC).addToLabels(metadata)
+	}
+
+	line := sink.addName(metadata, record.Message)
+
+	entry := client.Entry{
+		Timestamp:          record.Time,
+		Labels:             sink.entryLabels(record.Level),
+		Line:               line,
+		StructuredMetadata: metadata,
+	}
+
+	return sink.push(ctx, record.Level, entry)
+}
+
+// WithAttrs returns a new LokiSink with the given attributes added to the stream labels, prefixed by the current
+// groups. It implements the [logr.SlogSink] interface. It is safe to call concurrently from multiple goroutines.
+//
+//nolint:ireturn // Necessary to implement the logr.SlogSink interface.
+func (sink *LokiSink) WithAttrs(attrs []slog.Attr) logr.SlogSink {
+	newSink := sink.Clone()
+
+	for _, attr := range attrs {
+		addAttr(newSink.labels, newSink.groups, attr)
+	}
+
+	newSink.preformatLabels()
+
+	return newSink
+}
+
+// WithGroup returns a new LokiSink with the given group added. The group only applies to attributes added using
+// WithAttrs or logged using Handle, not to keys and values from the logr API. It implements the [logr.SlogSink]
+// interface. It is safe to call concurrently from multiple goroutines.
+//
+//nolint:ireturn // Necessary to implement the logr.SlogSink interface.
+func (sink *LokiSink) WithGroup(name string) logr.SlogSink {
+	if name == "" {
+		return sink
+	}
+
+	newSink := sink.Clone()
+	newSink.groups = append(newSink.groups, name)
+
+	return newSink
+}
+
+// addAttr adds the attribute to the map in place, prefixing its key with the groups joined by an underscore. Groups are
+// flattened, with groups without a key being inlined. Empty attributes are ignored. Values are rendered using
+// [slog.Value.String], the same as by the slog adapter, rather than like logr values.
+func addAttr(values map[string]string, groups []string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() != slog.KindGroup {
+		values[groupKey(groups, attr.Key)] = attr.Value.String()
+
+		return
+	}
+
+	if attr.Key != "" {
+		groups = append(groups[:len(groups):len(groups)], attr.Key)
+	}
+
+	for _, groupAttr := range attr.Value.Group() {
+		addAttr(values, groups, groupAttr)
+	}
+}
+
+// groupKey returns the key prefixed by the groups, joined by an underscore.
+func groupKey(groups []string, key string) string {
+	if len(groups) == 0 {
+		return key
+	}
+
+	return strings.Join(groups, "_") + "_" + key
+}
+
+// sourceFromPC returns the source for the given program counter, as recorded in a [slog.Record].
+func sourceFromPC(pc uintptr) *source {
+	frames := runtime.CallersFrames([]uintptr{pc})
+	frame, _ := frames.Next()
+
+	return &source{
+		function: frame.Function,
+		file:     frame.File,
+		line:     frame.Line,
+	}
+}
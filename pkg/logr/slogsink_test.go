@@ -0,0 +1,142 @@
+package logr
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+	lokislog "github.com/tslnc04/loki-logger/pkg/slog"
+)
+
+// logSlog logs the same record through any slog handler, so that the source is identical for all handlers.
+func logSlog(ctx context.Context, handler slog.Handler, level slog.Level) {
+	slog.New(handler).LogAttrs(ctx, level, defaultMessage, slog.String("key", "value"), slog.Int("count", 1),
+		slog.Any("struct", struct{ X int }{X: 1}),
+		slog.Group("group", slog.Bool("ok", true), slog.Group("", slog.String("inlined", "yes"))))
+}
+
+// streamsFor logs the record through the handler created by newHandler and returns the streams received by the fake
+// server.
+func streamsFor(
+	t *testing.T, newHandler func(lokiClient client.Client) slog.Handler, level slog.Level,
+) []push.Stream {
+	t.Helper()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.SetStrict(true)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	logSlog(t.Context(), newHandler(client.NewLokiClient(httpServer.URL+client.PushPath)), level)
+
+	streams := fakeServer.Streams()
+
+	return streams
+}
+
+//nolint:funlen // This function is long because it tests multiple cases, so not a code quality issue.
+func TestLokiSink_SlogRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		level    slog.Level
+		decorate func(handler slog.Handler) slog.Handler
+	}{
+		{
+			name:     "basic",
+			level:    slog.LevelInfo,
+			decorate: func(handler slog.Handler) slog.Handler { return handler },
+		},
+		{
+			name:  "debug-with-attrs",
+			level: slog.LevelDebug,
+			decorate: func(handler slog.Handler) slog.Handler {
+				return handler.WithAttrs([]slog.Attr{slog.String("app", "test")})
+			},
+		},
+		{
+			name:  "error-with-groups",
+			level: slog.LevelError,
+			decorate: func(handler slog.Handler) slog.Handler {
+				return handler.WithGroup("outer").
+					WithAttrs([]slog.Attr{slog.String("app", "test")}).
+					WithGroup("inner")
+			},
+		},
+		{
+			name:  "warn-with-empty-group",
+			level: slog.LevelWarn,
+			decorate: func(handler slog.Handler) slog.Handler {
+				return handler.WithGroup("").WithAttrs([]slog.Attr{slog.Group("nested", slog.Int("id", 1))})
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			slogStreams := streamsFor(t, func(lokiClient client.Client) slog.Handler {
+				return testCase.decorate(lokislog.NewHandler(lokiClient, &slog.HandlerOptions{
+					AddSource: true,
+					Level:     slog.Level(-maxLevel),
+				}))
+			}, testCase.level)
+
+			logrStreams := streamsFor(t, func(lokiClient client.Client) slog.Handler {
+				return testCase.decorate(logr.ToSlogHandler(logr.New(NewLokiSink(lokiClient, maxLevel))))
+			}, testCase.level)
+
+			require.Len(t, slogStreams, 1, "Expected number of slog streams to match")
+			require.Len(t, logrStreams, 1, "Expected number of logr streams to match")
+			require.Equal(t, slogStreams[0].Labels, logrStreams[0].Labels, "Expected labels to match")
+			require.Equal(t, slogStreams[0].Entries[0].Line, logrStreams[0].Entries[0].Line, "Expected lines to match")
+			require.ElementsMatch(t, slogStreams[0].Entries[0].StructuredMetadata,
+				logrStreams[0].Entries[0].StructuredMetadata, "Expected structured metadata to match")
+		})
+	}
+}
+
+func TestLokiSink_SlogVerbosity(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.SetStrict(true)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiSink := NewLokiSink(client.NewLokiClient(httpServer.URL+client.PushPath), 1)
+	logger := slog.New(logr.ToSlogHandler(logr.New(lokiSink).V(1)))
+
+	logger.Info(defaultMessage)
+	logger.Debug(defaultMessage)
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 1, "Expected only the info record to be enabled")
+	require.Equal(t, `{level="debug"}`, streams[0].Labels, "Expected V(1) to be applied to the level")
+}
+
+func TestLokiSink_WithGroup(t *testing.T) {
+	t.Parallel()
+
+	lokiSink := NewLokiSink(nil, 0)
+	require.Same(t, lokiSink, lokiSink.WithGroup(""))
+
+	groupSink, ok := lokiSink.WithGroup("group").WithAttrs([]slog.Attr{slog.String("key", "value")}).(*LokiSink)
+	require.True(t, ok)
+	require.Equal(t, []string{"group"}, groupSink.groups)
+	require.Equal(t, map[string]string{"group_key": "value"}, groupSink.labels)
+
+	// Ensure the original sink is not modified.
+	require.Empty(t, lokiSink.groups)
+	require.Empty(t, lokiSink.labels)
+}
@@ -0,0 +1,106 @@
+package logr
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+)
+
+// NoValue is the value used for a key without a value, i.e. the last key when an odd number of keys and values is
+// passed. It is the same marker used by funcr.
+const NoValue = "<no-value>"
+
+// addValues modifies the map in place by adding the keys and values, rendered using formatKey and formatValue. If there
+// are an odd number of keys and values, the last key is added with the value [NoValue].
+func addValues(values map[string]string, keysAndValues []any) {
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := formatKey(keysAndValues[i])
+
+		if i+1 == len(keysAndValues) {
+			values[key] = NoValue
+
+			break
+		}
+
+		values[key] = formatValue(keysAndValues[i+1])
+	}
+}
+
+// formatKey converts a key to a string. Keys should be strings, but anything else is converted using fmt.Sprint.
+func formatKey(key any) string {
+	if stringKey, ok := key.(string); ok {
+		return stringKey
+	}
+
+	return fmt.Sprint(key)
+}
+
+// formatValue converts a value to a string in a way similar to funcr:
+//
+//   - A [logr.Marshaler] is replaced by the result of MarshalLog, which is then rendered as below.
+//   - Strings are used unchanged.
+//   - Errors and [fmt.Stringer] values are rendered using their Error and String methods respectively.
+//   - Structs, maps, slices, and arrays, including pointers to them, are rendered as JSON.
+//   - Everything else is rendered using fmt.Sprint.
+//
+// If any of the methods called panics, the panic is recovered and the value is rendered as "<panic: ...>".
+func formatValue(value any) string {
+	var formatted string
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				formatted = fmt.Sprintf("<panic: %v>", recovered)
+			}
+		}()
+
+		formatted = renderValue(value)
+	}()
+
+	return formatted
+}
+
+// renderValue implements formatValue without recovering from panics.
+func renderValue(value any) string {
+	if marshaler, ok := value.(logr.Marshaler); ok {
+		value = marshaler.MarshalLog()
+	}
+
+	switch typed := value.(type) {
+	case nil:
+		return fmt.Sprint(typed)
+	case string:
+		return typed
+	case error:
+		return typed.Error()
+	case fmt.Stringer:
+		return typed.String()
+	}
+
+	if !isStructured(reflect.ValueOf(value)) {
+		return fmt.Sprint(value)
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(encoded)
+}
+
+// isStructured reports whether the value is a struct, map, slice, or array, or a non-nil pointer to one.
+func isStructured(value reflect.Value) bool {
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	switch value.Kind() { //nolint:exhaustive // All other kinds are not structured.
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,128 @@
+package logr
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+// objectRef is a logr.Marshaler similar to the object references used by Kubernetes controllers.
+type objectRef struct {
+	Name      string
+	Namespace string
+}
+
+func (ref objectRef) MarshalLog() any {
+	return struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	}{Name: ref.Name, Namespace: ref.Namespace}
+}
+
+// stringerMarshaler is a logr.Marshaler returning a fmt.Stringer, checking that the result is rendered as well.
+type stringerMarshaler struct{}
+
+func (stringerMarshaler) MarshalLog() any {
+	return time.Second
+}
+
+// panickingMarshaler is a logr.Marshaler that panics.
+type panickingMarshaler struct{}
+
+func (panickingMarshaler) MarshalLog() any {
+	panic("oops")
+}
+
+type point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+//nolint:funlen // Most of the function is test cases, no need to worry about length.
+func TestFormatValue(t *testing.T) {
+	t.Parallel()
+
+	var nilPoint *point
+
+	testCases := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{name: "nil", value: nil, expected: "<nil>"},
+		{name: "string", value: "value", expected: "value"},
+		{name: "int", value: 42, expected: "42"},
+		{name: "bool", value: true, expected: "true"},
+		{name: "error", value: errors.New("failed"), expected: "failed"},
+		{name: "stringer", value: time.Minute, expected: "1m0s"},
+		{name: "struct", value: point{X: 1, Y: 2}, expected: `{"x":1,"y":2}`},
+		{name: "pointer", value: &point{X: 1, Y: 2}, expected: `{"x":1,"y":2}`},
+		{name: "nil-pointer", value: nilPoint, expected: "<nil>"},
+		{name: "map", value: map[string]int{"b": 2, "a": 1}, expected: `{"a":1,"b":2}`},
+		{name: "slice", value: []string{"a", "b"}, expected: `["a","b"]`},
+		{name: "unencodable", value: []func(){nil}, expected: "[<nil>]"},
+		{
+			name:     "marshaler",
+			value:    objectRef{Name: "pod", Namespace: "default"},
+			expected: `{"name":"pod","namespace":"default"}`,
+		},
+		{name: "marshaler-stringer", value: stringerMarshaler{}, expected: "1s"},
+		{name: "marshaler-panic", value: panickingMarshaler{}, expected: "<panic: oops>"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testCase.expected, formatValue(testCase.value))
+		})
+	}
+}
+
+func TestAddValues(t *testing.T) {
+	t.Parallel()
+
+	values := map[string]string{}
+	addValues(values, []any{"key", "value", 1, point{X: 1}, "dangling"})
+
+	require.Equal(t, map[string]string{
+		"key":      "value",
+		"1":        `{"x":1,"y":0}`,
+		"dangling": NoValue,
+	}, values)
+}
+
+func TestLokiSinkLogging_Values(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.SetStrict(true)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiSink := NewLokiSink(client.NewLokiClient(httpServer.URL+client.PushPath), 0)
+	logger := logr.New(lokiSink).WithValues("ref", objectRef{Name: "pod", Namespace: "default"}, "dangling")
+
+	logger.Error(errors.New("failed"), defaultMessage, "count", 1, "odd")
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 1, "Expected number of streams to match")
+	require.Equal(t, `{dangling="<no-value>", level="error", ref="{\"name\":\"pod\",\"namespace\":\"default\"}"}`,
+		streams[0].Labels)
+
+	metadata := map[string]string{}
+	for _, label := range streams[0].Entries[0].StructuredMetadata {
+		metadata[label.Name] = label.Value
+	}
+
+	require.Equal(t, "1", metadata["count"])
+	require.Equal(t, NoValue, metadata["odd"])
+	require.Equal(t, "failed", metadata[ErrorKey])
+}
@@ -0,0 +1,258 @@
+// Package logrus provides a [logrus.Hook] that sends log entries to a Loki instance.
+//
+// The hook is added to a logger using [logrus.Logger.AddHook]. Since logrus fires hooks synchronously, pass a client
+// such as the one from the retry package to deliver entries asynchronously. Such a client is flushed after entries at
+// the fatal and panic levels, since logrus exits or panics right after firing the hooks for them.
+//
+//	hook := lokilogrus.NewHook(lokiClient, map[string]string{"app": "example"}).WithLabelFields("component")
+//	logger := logrus.New()
+//	logger.AddHook(hook)
+//
+// # Labels vs Metadata
+//
+// The level of each entry is added to the stream labels, together with the labels passed to [NewHook] and any fields
+// configured using [Hook.WithLabelFields]. All other fields are added as structured metadata.
+package logrus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tslnc04/loki-logger/pkg/client"
+)
+
+const (
+	// LevelKey is the key added to the stream labels for a log line unless configured otherwise using
+	// [Hook.WithLevelLabel]. Its value is the name of the level from [client.DefaultLevelMapper], e.g. "info" or
+	// "warn". The logrus levels fatal and panic are both mapped to [client.LevelCritical].
+	LevelKey = client.DefaultLevelKey
+	// SourceKey is the prefix for the keys added to the structured metadata when the logger reports the caller. The
+	// actual keys used are SourceKey+"_function", SourceKey+"_file", and SourceKey+"_line", the same as in the slog
+	// adapter.
+	SourceKey = "source"
+)
+
+// DefaultFlushTimeout is how long [Hook.Fire] waits for the client to flush after a fatal or panic entry, unless
+// changed using [Hook.WithFlushTimeout]. It keeps logrus from blocking forever before exiting if Loki is unreachable.
+const DefaultFlushTimeout = 5 * time.Second
+
+// Hook is a [logrus.Hook] that sends log entries to a Loki instance. It is safe to use concurrently.
+type Hook struct {
+	lokiClient client.Client
+	labels     map[string]string
+	// labelFields is the set of fields that are added to the stream labels instead of the structured metadata.
+	labelFields  map[string]struct{}
+	levels       []logrus.Level
+	levelLabel   client.LevelLabel
+	flushTimeout time.Duration
+}
+
+// Assert that Hook implements the [logrus.Hook] interface.
+var _ logrus.Hook = (*Hook)(nil)
+
+// NewHook creates a new Hook with the given client and labels. Labels may be nil. The hook fires for all levels.
+func NewHook(lokiClient client.Client, labels map[string]string) *Hook {
+	return &Hook{
+		lokiClient:   lokiClient,
+		labels:       maps.Clone(labels),
+		labelFields:  make(map[string]struct{}),
+		levels:       logrus.AllLevels,
+		levelLabel:   client.DefaultLevelLabel(),
+		flushTimeout: DefaultFlushTimeout,
+	}
+}
+
+// WithLabels returns a new Hook with the labels added. Keys that already exist will be overwritten. It is safe to call
+// concurrently from multiple goroutines.
+func (hook *Hook) WithLabels(labels map[string]string) *Hook {
+	newHook := hook.Clone()
+
+	if newHook.labels == nil {
+		newHook.labels = make(map[string]string, len(labels))
+	}
+
+	maps.Copy(newHook.labels, labels)
+
+	return newHook
+}
+
+// WithLabelFields returns a new Hook that adds the given fields of each entry to the stream labels instead of the
+// structured metadata. It is safe to call concurrently from multiple goroutines.
+func (hook *Hook) WithLabelFields(fields ...string) *Hook {
+	newHook := hook.Clone()
+
+	for _, field := range fields {
+		newHook.labelFields[field] = struct{}{}
+	}
+
+	return newHook
+}
+
+// WithLevels returns a new Hook that only fires for the given levels. It is safe to call concurrently from multiple
+// goroutines.
+func (hook *Hook) WithLevels(levels ...logrus.Level) *Hook {
+	newHook := hook.Clone()
+	newHook.levels = levels
+
+	return newHook
+}
+
+// WithLevelLabel returns a new Hook that adds the level to the stream labels as configured by the given LevelLabel.
+// Use a LevelLabel with an empty key to omit the level label. It is safe to call concurrently from multiple goroutines.
+func (hook *Hook) WithLevelLabel(levelLabel client.LevelLabel) *Hook {
+	newHook := hook.Clone()
+	newHook.levelLabel = levelLabel
+
+	return newHook
+}
+
+// WithFlushTimeout returns a new Hook that waits at most the given duration for the client to flush after a fatal or
+// panic entry. It defaults to [DefaultFlushTimeout], and a timeout of zero waits until the flush completes. It is safe
+// to call concurrently from multiple goroutines.
+func (hook *Hook) WithFlushTimeout(timeout time.Duration) *Hook {
+	newHook := hook.Clone()
+	newHook.flushTimeout = timeout
+
+	return newHook
+}
+
+// Clone returns a copy of the Hook, sharing only the Loki client. It is safe to call concurrently from multiple
+// goroutines.
+func (hook *Hook) Clone() *Hook {
+	return &Hook{
+		lokiClient:   hook.lokiClient,
+		labels:       maps.Clone(hook.labels),
+		labelFields:  maps.Clone(hook.labelFields),
+		levels:       hook.levels,
+		levelLabel:   hook.levelLabel,
+		flushTimeout: hook.flushTimeout,
+	}
+}
+
+// Levels returns the levels the hook fires for. It implements the [logrus.Hook] interface.
+func (hook *Hook) Levels() []logrus.Level {
+	return hook.levels
+}
+
+// Fire converts the entry to a [client.Entry] and pushes it to Loki. If the entry has a context, it is passed on to
+// the client. For fatal and panic entries, the client is flushed afterwards if it implements the [client.Flusher]
+// interface, waiting at most the flush timeout, see [Hook.WithFlushTimeout]. It implements the [logrus.Hook]
+// interface.
+func (hook *Hook) Fire(entry *logrus.Entry) error {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	err := hook.lokiClient.Push(ctx, hook.entryToEntry(entry))
+
+	if entry.Level <= logrus.FatalLevel {
+		err = errors.Join(err, hook.flush())
+	}
+
+	return err
+}
+
+// flush flushes the client if it implements the [client.Flusher] interface, waiting at most the flush timeout.
+func (hook *Hook) flush() error {
+	flusher, ok := hook.lokiClient.(client.Flusher)
+	if !ok {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	if hook.flushTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, hook.flushTimeout)
+		defer cancel()
+	}
+
+	return flusher.Flush(ctx)
+}
+
+// entryToEntry converts the logrus entry to the Entry used by the Loki client.
+func (hook *Hook) entryToEntry(entry *logrus.Entry) client.Entry {
+	labels := make(map[string]string, len(hook.labels)+1)
+	maps.Copy(labels, hook.labels)
+
+	metadata := make(map[string]string, len(entry.Data))
+
+	for key, value := range entry.Data {
+		if _, ok := hook.labelFields[key]; ok {
+			labels[key] = formatValue(value)
+		} else {
+			metadata[key] = formatValue(value)
+		}
+	}
+
+	hook.levelLabel.Apply(labels, slogLevel(entry.Level))
+
+	if entry.HasCaller() {
+		addCaller(metadata, entry.Caller)
+	}
+
+	return client.Entry{
+		Timestamp:          entry.Time,
+		Labels:             client.LabelMap(labels),
+		Line:               entry.Message,
+		StructuredMetadata: metadata,
+	}
+}
+
+// slogLevel converts the logrus level to the [slog.Level] scale used by the level label. Levels above trace, i.e.
+// custom levels with higher verbosity, are mapped to [client.LevelTrace] as well.
+func slogLevel(level logrus.Level) slog.Level {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return client.LevelCritical
+	case logrus.ErrorLevel:
+		return slog.LevelError
+	case logrus.WarnLevel:
+		return slog.LevelWarn
+	case logrus.InfoLevel:
+		return slog.LevelInfo
+	case logrus.DebugLevel:
+		return slog.LevelDebug
+	case logrus.TraceLevel:
+		return client.LevelTrace
+	default:
+		return client.LevelTrace
+	}
+}
+
+// formatValue converts the value of a field to a string. Errors are converted using their Error method and all other
+// values using fmt.Sprint.
+func formatValue(value any) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case error:
+		return typed.Error()
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+// addCaller adds the caller to the metadata, ignoring any values that are empty. It will modify the metadata in place.
+func addCaller(metadata map[string]string, caller *runtime.Frame) {
+	if caller.Function != "" {
+		metadata[SourceKey+"_function"] = caller.Function
+	}
+
+	if caller.File != "" {
+		metadata[SourceKey+"_file"] = caller.File
+	}
+
+	if caller.Line != 0 {
+		metadata[SourceKey+"_line"] = strconv.Itoa(caller.Line)
+	}
+}
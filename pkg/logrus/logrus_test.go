@@ -0,0 +1,247 @@
+package logrus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/client/dedup"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+const defaultMessage = "Hello, world!"
+
+// newLogger creates a logrus logger that discards its own output and sends all entries to the hook.
+func newLogger(hook *Hook) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.TraceLevel)
+	logger.AddHook(hook)
+
+	return logger
+}
+
+func TestHook_With(t *testing.T) {
+	t.Parallel()
+
+	hook := NewHook(nil, nil)
+	require.Empty(t, hook.labels)
+	require.Equal(t, logrus.AllLevels, hook.Levels())
+
+	withLabels := hook.WithLabels(map[string]string{"app": "test"}).
+		WithLabelFields("component").
+		WithLevels(logrus.ErrorLevel)
+	require.Equal(t, map[string]string{"app": "test"}, withLabels.labels)
+	require.Contains(t, withLabels.labelFields, "component")
+	require.Equal(t, []logrus.Level{logrus.ErrorLevel}, withLabels.Levels())
+
+	// Ensure the original hook is not modified.
+	require.Empty(t, hook.labels)
+	require.Empty(t, hook.labelFields)
+	require.Equal(t, logrus.AllLevels, hook.Levels())
+}
+
+//nolint:funlen // This function is long because it tests multiple cases, so not a code quality issue.
+func TestHookLogging(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		log      func(logger *logrus.Logger)
+		expected client.Entry
+	}{
+		{
+			name: "basic",
+			log: func(logger *logrus.Logger) {
+				logger.WithFields(logrus.Fields{"key": "value", "count": 1}).Info(defaultMessage)
+			},
+			expected: client.Entry{
+				Labels:             client.LabelMap{"app": "test", LevelKey: "info"},
+				Line:               defaultMessage,
+				StructuredMetadata: map[string]string{"key": "value", "count": "1"},
+			},
+		},
+		{
+			name: "label-fields",
+			log: func(logger *logrus.Logger) {
+				logger.WithField("component", "db").Warn(defaultMessage)
+			},
+			expected: client.Entry{
+				Labels: client.LabelMap{"app": "test", "component": "db", LevelKey: "warn"},
+				Line:   defaultMessage,
+			},
+		},
+		{
+			name: "error",
+			log: func(logger *logrus.Logger) {
+				logger.WithError(errors.New("test error")).Error(defaultMessage)
+			},
+			expected: client.Entry{
+				Labels:             client.LabelMap{"app": "test", LevelKey: "error"},
+				Line:               defaultMessage,
+				StructuredMetadata: map[string]string{logrus.ErrorKey: "test error"},
+			},
+		},
+		{
+			name: "trace-with-context",
+			log: func(logger *logrus.Logger) {
+				logger.WithContext(context.Background()).Trace(defaultMessage)
+			},
+			expected: client.Entry{
+				Labels: client.LabelMap{"app": "test", LevelKey: "trace"},
+				Line:   defaultMessage,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+			hook := NewHook(lokiClient, map[string]string{"app": "test"}).WithLabelFields("component")
+			testCase.log(newLogger(hook))
+
+			streams := fakeServer.Streams()
+
+			require.Len(t, streams, 1, "Expected number of streams to match")
+			client.AssertStreamMatchesEntry(t, testCase.expected, streams[0])
+		})
+	}
+}
+
+func TestHookLoggingCaller(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	logger := newLogger(NewHook(client.NewLokiClient(httpServer.URL+client.PushPath), nil))
+	logger.SetReportCaller(true)
+	logger.Info(defaultMessage)
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 1, "Expected number of streams to match")
+	require.Len(t, streams[0].Entries, 1, "Expected number of entries to match")
+
+	metadata := make(map[string]string)
+	for _, label := range streams[0].Entries[0].StructuredMetadata {
+		metadata[label.Name] = label.Value
+	}
+
+	require.Equal(t, "github.com/tslnc04/loki-logger/pkg/logrus.TestHookLoggingCaller", metadata[SourceKey+"_function"])
+	require.Contains(t, metadata[SourceKey+"_file"], "logrus_test.go")
+	require.NotEmpty(t, metadata[SourceKey+"_line"])
+}
+
+func TestHook_FireLevels(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	hook := NewHook(client.NewLokiClient(httpServer.URL+client.PushPath), nil).WithLevels(logrus.ErrorLevel)
+	logger := newLogger(hook)
+	logger.Info(defaultMessage)
+	logger.Error(defaultMessage)
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 1, "Expected number of streams to match")
+	client.AssertStreamMatchesEntry(t, client.Entry{
+		Labels: client.LabelMap{LevelKey: "error"},
+		Line:   defaultMessage,
+	}, streams[0])
+}
+
+func TestHook_FireError(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.FailNext(1)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	hook := NewHook(client.NewLokiClient(httpServer.URL+client.PushPath), nil)
+
+	err := hook.Fire(&logrus.Entry{Logger: logrus.New(), Level: logrus.InfoLevel, Message: defaultMessage})
+	require.Error(t, err)
+}
+
+func TestHook_WithLevelLabel(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	hook := NewHook(client.NewLokiClient(httpServer.URL+client.PushPath), nil)
+	customHook := hook.WithLevelLabel(client.LevelLabel{Key: "detected_level"})
+
+	require.Equal(t, client.DefaultLevelLabel(), hook.levelLabel, "Expected the original hook to not be modified")
+
+	require.NoError(t, hook.Fire(&logrus.Entry{Level: logrus.PanicLevel, Message: defaultMessage}))
+	require.NoError(t, customHook.Fire(&logrus.Entry{Level: logrus.DebugLevel, Message: defaultMessage}))
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 2, "Expected number of streams to match")
+	require.Equal(t, `{level="critical"}`, streams[0].Labels)
+	require.Equal(t, `{detected_level="debug"}`, streams[1].Labels)
+}
+
+func TestHook_FireFlushes(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	logger := newLogger(NewHook(dedup.NewDedupClient(lokiClient).WithWindow(time.Hour), nil))
+
+	exitCode := 0
+	logger.ExitFunc = func(code int) { exitCode = code }
+
+	logger.Error(defaultMessage)
+	require.Empty(t, fakeServer.Streams(), "Expected the error entry to be pending")
+
+	logger.Fatal(defaultMessage)
+	require.Equal(t, 1, exitCode)
+	require.Len(t, fakeServer.Streams(), 2, "Expected the pending entries to be flushed before exiting")
+
+	require.Panics(t, func() { logger.Panic(defaultMessage) })
+	require.Len(t, fakeServer.Streams(), 3, "Expected the pending entries to be flushed before panicking")
+}
+
+func TestHook_WithFlushTimeout(t *testing.T) {
+	t.Parallel()
+
+	hook := NewHook(nil, nil)
+	require.Equal(t, DefaultFlushTimeout, hook.flushTimeout)
+
+	withTimeout := hook.WithFlushTimeout(time.Second)
+	require.Equal(t, time.Second, withTimeout.flushTimeout)
+	require.Equal(t, time.Second, withTimeout.Clone().flushTimeout)
+
+	// The original hook should not be modified.
+	require.Equal(t, DefaultFlushTimeout, hook.flushTimeout)
+}
@@ -0,0 +1,46 @@
+package lokitest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// AssertEntries asserts that the entries matching the LogQL query equal the expected entries, in the order
+// they were received. Timestamps are only compared for expected entries with a non-zero timestamp. Empty structured
+// metadata is expected as nil.
+func AssertEntries(t testing.TB, server *Server, query string, expected ...Entry) {
+	t.Helper()
+
+	actual, err := server.Select(query)
+	require.NoError(t, err, "expected query to be valid")
+	require.Len(t, actual, len(expected), "expected number of entries matching %s to match", query)
+
+	for i, expectedEntry := range expected {
+		if expectedEntry.Timestamp.IsZero() {
+			actual[i].Timestamp = expectedEntry.Timestamp
+		}
+
+		require.Equal(t, expectedEntry, actual[i], "expected entry %d matching %s to match", i, query)
+	}
+}
+
+// AssertLines asserts that the lines of the entries matching the LogQL query equal the expected lines, in
+// the order they were received.
+func AssertLines(t testing.TB, server *Server, query string, expected ...string) {
+	t.Helper()
+
+	entries, err := server.Select(query)
+	require.NoError(t, err, "expected query to be valid")
+
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, entry.Line)
+	}
+
+	if expected == nil {
+		expected = []string{}
+	}
+
+	require.Equal(t, expected, lines, "expected lines matching %s to match", query)
+}
@@ -0,0 +1,139 @@
+package lokitest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/tslnc04/loki-logger/pkg/internal/labels"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	protov2 "google.golang.org/protobuf/proto"
+)
+
+// decodeContent removes the Content-Encoding of a request body. Like Loki, it supports gzip and deflate. It also
+// supports zstd, which Loki only accepts through a gateway.
+func decodeContent(contentEncoding string, body io.Reader) ([]byte, error) {
+	var err error
+
+	switch contentEncoding {
+	case "":
+	case "gzip":
+		body, err = gzip.NewReader(body)
+	case "deflate":
+		body = flate.NewReader(body)
+	case "zstd":
+		var decoder *zstd.Decoder
+
+		decoder, err = zstd.NewReader(body)
+		if err == nil {
+			defer decoder.Close()
+
+			body = decoder
+		}
+	default:
+		return nil, fmt.Errorf("Content-Encoding %q not supported", contentEncoding) //nolint:staticcheck // Loki's message.
+	}
+
+	if err != nil {
+		return nil, errors.New("failed to decode request body")
+	}
+
+	decoded, err := io.ReadAll(body)
+	if err != nil {
+		return nil, errors.New("failed to decode request body")
+	}
+
+	return decoded, nil
+}
+
+// decodePush decodes the body of a request to the Loki push API.
+func decodePush(body []byte) ([]push.Stream, error) {
+	decoded, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, errors.New("failed to decode request body")
+	}
+
+	pushRequest := push.PushRequest{}
+
+	err = proto.Unmarshal(decoded, &pushRequest)
+	if err != nil {
+		return nil, errors.New("failed to unmarshal request body")
+	}
+
+	return pushRequest.Streams, nil
+}
+
+// decodeOTLP decodes the body of a request to the OTLP logs endpoint and converts it to streams. There is one stream
+// per log record.
+func decodeOTLP(contentType string, body []byte) ([]push.Stream, error) {
+	logsData := logsv1.LogsData{}
+
+	var err error
+	if strings.HasPrefix(contentType, "application/json") {
+		err = protojson.Unmarshal(body, &logsData)
+	} else {
+		err = protov2.Unmarshal(body, &logsData)
+	}
+
+	if err != nil {
+		return nil, errors.New("failed to unmarshal request body")
+	}
+
+	var streams []push.Stream
+
+	for _, resourceLogs := range logsData.GetResourceLogs() {
+		resourceLabels := attributesToMap(resourceLogs.GetResource().GetAttributes())
+
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			for _, record := range scopeLogs.GetLogRecords() {
+				streams = append(streams, logRecordToStream(resourceLabels, record))
+			}
+		}
+	}
+
+	return streams, nil
+}
+
+// logRecordToStream converts a single OTLP log record to a stream with a single entry.
+func logRecordToStream(resourceLabels map[string]string, record *logsv1.LogRecord) push.Stream {
+	streamLabels := maps.Clone(resourceLabels)
+	if record.GetSeverityText() != "" {
+		streamLabels[levelKey] = record.GetSeverityText()
+	}
+
+	var metadata push.LabelsAdapter
+	for key, value := range attributesToMap(record.GetAttributes()) {
+		metadata = append(metadata, push.LabelAdapter{Name: key, Value: value})
+	}
+
+	return push.Stream{
+		Labels: labels.Format(streamLabels),
+		Entries: []push.Entry{{
+			Timestamp:          time.Unix(0, int64(record.GetTimeUnixNano())),
+			Line:               record.GetBody().GetStringValue(),
+			StructuredMetadata: metadata,
+		}},
+	}
+}
+
+// attributesToMap converts OTLP attributes to a map, only keeping their string values.
+func attributesToMap(attributes []*commonv1.KeyValue) map[string]string {
+	values := make(map[string]string, len(attributes))
+
+	for _, attribute := range attributes {
+		values[attribute.GetKey()] = attribute.GetValue().GetStringValue()
+	}
+
+	return values
+}
@@ -0,0 +1,482 @@
+package lokitest
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/tslnc04/loki-logger/pkg/internal/labels"
+)
+
+// ErrInvalidQuery is returned when a LogQL query cannot be parsed.
+var ErrInvalidQuery = errors.New("invalid query")
+
+const (
+	// ErrorLabel is the label added to entries for which a parser stage failed, the same as in Loki.
+	ErrorLabel = "__error__"
+	// JSONParserErr is the value of [ErrorLabel] when the line is not a JSON object.
+	JSONParserErr = "JSONParserErr"
+	// LogfmtParserErr is the value of [ErrorLabel] when the line is not valid logfmt.
+	LogfmtParserErr = "LogfmtParserErr"
+	// extractedSuffix is appended to extracted labels that conflict with the labels of the stream, the same as in Loki.
+	extractedSuffix = "_extracted"
+)
+
+// Query is a parsed LogQL log query, consisting of a stream selector and a pipeline of stages. It supports a subset of
+// LogQL that is useful for checking logs in tests:
+//
+//   - Line filters: `|= "text"`, `!= "text"`, `|~ "regexp"`, and `!~ "regexp"`. Regular expressions are not anchored.
+//   - Parsers: `| json`, which flattens nested objects by joining keys with an underscore, and `| logfmt`. Parsed
+//     labels that conflict with stream labels get the suffix `_extracted`. Lines that cannot be parsed get the label
+//     `__error__`.
+//   - Label filters: `| name = "value"` with the operators `=`, `!=`, `=~`, and `!~`, or numeric comparisons such as
+//     `| status >= 400` with `==`, `!=`, `>`, `>=`, `<`, and `<=`. Multiple filters can be combined using `and` or `,`.
+//     Entries with values that are not numbers never match a numeric comparison.
+//
+// Structured metadata can be used in label filters the same way as stream labels and labels extracted by parsers.
+type Query struct {
+	Selector Selector
+	stages   []stage
+}
+
+// stage is a single stage of a pipeline. It returns false if the entry should be dropped. The labels may be modified
+// in place.
+type stage func(line string, labels map[string]string, metadata map[string]string) bool
+
+// ParseQuery parses a LogQL log query. It returns an error wrapping [ErrInvalidQuery] if the query cannot be parsed or
+// uses parts of LogQL that are not supported.
+func ParseQuery(input string) (*Query, error) {
+	scanner := &scanner{input: input, sentinel: ErrInvalidQuery}
+
+	selector, err := scanner.selector()
+	if err != nil {
+		return nil, err
+	}
+
+	query := &Query{Selector: selector}
+
+	for !scanner.done() {
+		stage, err := scanner.stage()
+		if err != nil {
+			return nil, err
+		}
+
+		query.stages = append(query.stages, stage)
+	}
+
+	return query, nil
+}
+
+// Evaluate runs the query on the streams and returns the matching entries in order. The labels of the entries are the
+// stream labels and any labels extracted by parsers.
+func (query *Query) Evaluate(streams []push.Stream) []Entry {
+	entries := []Entry{}
+
+	for _, stream := range streams {
+		streamLabels, err := labels.Parse(stream.Labels)
+		if err != nil || !query.Selector.Matches(streamLabels) {
+			continue
+		}
+
+		for _, entry := range stream.Entries {
+			metadata := labelsAdapterToMap(entry.StructuredMetadata)
+
+			entryLabels, ok := query.process(entry.Line, streamLabels, metadata)
+			if !ok {
+				continue
+			}
+
+			entries = append(entries, Entry{
+				Labels:             entryLabels,
+				Timestamp:          entry.Timestamp,
+				Line:               entry.Line,
+				StructuredMetadata: metadata,
+			})
+		}
+	}
+
+	return entries
+}
+
+// process runs the pipeline on a single entry, returning its labels and whether it was kept.
+func (query *Query) process(line string, streamLabels, metadata map[string]string) (map[string]string, bool) {
+	entryLabels := maps.Clone(streamLabels)
+
+	for _, stage := range query.stages {
+		if !stage(line, entryLabels, metadata) {
+			return nil, false
+		}
+	}
+
+	return entryLabels, true
+}
+
+// stage parses a single pipeline stage at the current position.
+func (scanner *scanner) stage() (stage, error) {
+	switch {
+	case scanner.consume("|="):
+		return scanner.lineFilter(false, false)
+	case scanner.consume("!="):
+		return scanner.lineFilter(true, false)
+	case scanner.consume("|~"):
+		return scanner.lineFilter(false, true)
+	case scanner.consume("!~"):
+		return scanner.lineFilter(true, true)
+	case scanner.consume("|"):
+		return scanner.pipeStage()
+	default:
+		return nil, scanner.errorf("expected pipeline stage")
+	}
+}
+
+// lineFilter parses the argument of a line filter.
+func (scanner *scanner) lineFilter(negate, isRegexp bool) (stage, error) {
+	value, err := scanner.quoted()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := func(line string) bool { return strings.Contains(line, value) }
+
+	if isRegexp {
+		compiled, err := regexp.Compile(value)
+		if err != nil {
+			return nil, scanner.errorf("invalid regular expression %q", value)
+		}
+
+		matches = compiled.MatchString
+	}
+
+	return func(line string, _, _ map[string]string) bool {
+		return matches(line) != negate
+	}, nil
+}
+
+// pipeStage parses a parser or label filter stage following a pipe.
+func (scanner *scanner) pipeStage() (stage, error) {
+	start := scanner.pos
+
+	name, ok := scanner.identifier()
+	if !ok {
+		return nil, scanner.errorf("expected parser or label filter")
+	}
+
+	if !scanner.peekLabelOperator() {
+		switch name {
+		case "json":
+			return parserStage(parseJSON, JSONParserErr), nil
+		case "logfmt":
+			return parserStage(parseLogfmt, LogfmtParserErr), nil
+		default:
+			return nil, scanner.errorf("unsupported stage %q", name)
+		}
+	}
+
+	scanner.pos = start
+
+	return scanner.labelFilters()
+}
+
+// labelFilters parses one or more label filters combined using `and` or `,`.
+func (scanner *scanner) labelFilters() (stage, error) {
+	var filters []func(labels map[string]string) bool
+
+	for {
+		filter, err := scanner.labelFilter()
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+
+		if !scanner.consume(",") && !scanner.consumeKeyword("and") {
+			break
+		}
+	}
+
+	return func(_ string, entryLabels, metadata map[string]string) bool {
+		combined := entryLabels
+		if len(metadata) > 0 {
+			combined = maps.Clone(metadata)
+			maps.Copy(combined, entryLabels)
+		}
+
+		for _, filter := range filters {
+			if !filter(combined) {
+				return false
+			}
+		}
+
+		return true
+	}, nil
+}
+
+// labelFilter parses a single string or numeric label filter.
+func (scanner *scanner) labelFilter() (func(labels map[string]string) bool, error) {
+	name, ok := scanner.identifier()
+	if !ok {
+		return nil, scanner.errorf("expected label name")
+	}
+
+	if compare, ok := scanner.numericOperator(); ok {
+		number, err := scanner.number()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(labels map[string]string) bool {
+			value, err := strconv.ParseFloat(labels[name], 64)
+
+			return err == nil && compare(value, number)
+		}, nil
+	}
+
+	matchType, ok := scanner.matchType()
+	if !ok {
+		return nil, scanner.errorf("expected operator after label %q", name)
+	}
+
+	value, err := scanner.quoted()
+	if err != nil {
+		return nil, err
+	}
+
+	matcher, err := NewMatcher(matchType, name, value)
+	if err != nil {
+		return nil, scanner.errorf("invalid regular expression %q", value)
+	}
+
+	return func(labels map[string]string) bool {
+		return matcher.Matches(labels[name])
+	}, nil
+}
+
+// numericOperators are the numeric comparison operators of label filters. Longer operators must come first.
+var numericOperators = []struct {
+	token   string
+	compare func(value, number float64) bool
+}{
+	{">=", func(value, number float64) bool { return value >= number }},
+	{"<=", func(value, number float64) bool { return value <= number }},
+	{"==", func(value, number float64) bool { return value == number }},
+	{"!=", func(value, number float64) bool { return value != number }},
+	{">", func(value, number float64) bool { return value > number }},
+	{"<", func(value, number float64) bool { return value < number }},
+	{"=", func(value, number float64) bool { return value == number }},
+}
+
+// numericOperator parses a numeric comparison operator followed by a number, returning its comparison function. If the
+// operator is not followed by a number, such as `!= "value"`, it does not advance and leaves it to matchType.
+func (scanner *scanner) numericOperator() (func(value, number float64) bool, bool) {
+	start := scanner.pos
+
+	if scanner.consume("=~") || scanner.consume("!~") {
+		scanner.pos = start
+
+		return nil, false
+	}
+
+	for _, operator := range numericOperators {
+		if !scanner.consume(operator.token) {
+			continue
+		}
+
+		scanner.skipSpace()
+
+		if rest := scanner.rest(); rest != "" && isNumberByte(rest[0]) {
+			return operator.compare, true
+		}
+
+		break
+	}
+
+	scanner.pos = start
+
+	return nil, false
+}
+
+// number parses an unquoted number.
+func (scanner *scanner) number() (float64, error) {
+	scanner.skipSpace()
+
+	start := scanner.pos
+
+	for scanner.pos < len(scanner.input) && isNumberByte(scanner.input[scanner.pos]) {
+		scanner.pos++
+	}
+
+	number, err := strconv.ParseFloat(scanner.input[start:scanner.pos], 64)
+	if err != nil {
+		return 0, scanner.errorf("expected number")
+	}
+
+	return number, nil
+}
+
+// peekLabelOperator reports whether the input continues with a label filter operator without advancing past it.
+func (scanner *scanner) peekLabelOperator() bool {
+	scanner.skipSpace()
+
+	rest := scanner.rest()
+
+	return rest != "" && strings.ContainsRune("=!<>", rune(rest[0]))
+}
+
+// consumeKeyword advances past the keyword if the input continues with it as a whole word.
+func (scanner *scanner) consumeKeyword(keyword string) bool {
+	start := scanner.pos
+
+	word, ok := scanner.identifier()
+	if ok && word == keyword {
+		return true
+	}
+
+	scanner.pos = start
+
+	return false
+}
+
+// parserStage returns a stage that adds the labels extracted by the parser. If the parser fails, the error label is
+// set to errorValue instead. Extracted labels that conflict with existing labels get the suffix `_extracted`.
+func parserStage(parse func(line string) (map[string]string, bool), errorValue string) stage {
+	return func(line string, entryLabels, _ map[string]string) bool {
+		extracted, ok := parse(line)
+		if !ok {
+			entryLabels[ErrorLabel] = errorValue
+
+			return true
+		}
+
+		for name, value := range extracted {
+			name = sanitizeLabelName(name)
+			if _, ok := entryLabels[name]; ok {
+				name += extractedSuffix
+			}
+
+			entryLabels[name] = value
+		}
+
+		return true
+	}
+}
+
+// parseJSON extracts the fields of a JSON object as labels. Nested objects are flattened by joining the keys with an
+// underscore, while arrays are ignored.
+func parseJSON(line string) (map[string]string, bool) {
+	var object map[string]any
+	if err := json.Unmarshal([]byte(line), &object); err != nil {
+		return nil, false
+	}
+
+	extracted := make(map[string]string, len(object))
+	flattenJSON(extracted, "", object)
+
+	return extracted, true
+}
+
+// flattenJSON adds the fields of the object to the labels, prefixing their keys.
+func flattenJSON(extracted map[string]string, prefix string, object map[string]any) {
+	for key, value := range object {
+		key = prefix + key
+
+		switch typed := value.(type) {
+		case map[string]any:
+			flattenJSON(extracted, key+"_", typed)
+		case []any:
+		case nil:
+			extracted[key] = ""
+		case string:
+			extracted[key] = typed
+		case float64:
+			extracted[key] = strconv.FormatFloat(typed, 'f', -1, 64)
+		case bool:
+			extracted[key] = strconv.FormatBool(typed)
+		}
+	}
+}
+
+// parseLogfmt extracts the key-value pairs of a logfmt line as labels. Values may be quoted as Go strings. Keys without
+// a value are ignored.
+func parseLogfmt(line string) (map[string]string, bool) {
+	extracted := map[string]string{}
+	rest := line
+
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return extracted, true
+		}
+
+		end := strings.IndexAny(rest, "= \t")
+		if end < 0 {
+			return extracted, true
+		}
+
+		key := rest[:end]
+		if key == "" {
+			return nil, false
+		}
+
+		rest = rest[end:]
+		if rest[0] != '=' {
+			continue
+		}
+
+		rest = rest[1:]
+
+		value, remaining, ok := logfmtValue(rest)
+		if !ok {
+			return nil, false
+		}
+
+		extracted[key] = value
+		rest = remaining
+	}
+}
+
+// logfmtValue parses a quoted or bare logfmt value from the start of the input, returning the rest of the input.
+func logfmtValue(input string) (string, string, bool) {
+	if strings.HasPrefix(input, `"`) {
+		quoted, err := strconv.QuotedPrefix(input)
+		if err != nil {
+			return "", "", false
+		}
+
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return "", "", false
+		}
+
+		return value, input[len(quoted):], true
+	}
+
+	end := strings.IndexAny(input, " \t")
+	if end < 0 {
+		end = len(input)
+	}
+
+	return input[:end], input[end:], true
+}
+
+// sanitizeLabelName replaces all characters that are not allowed in label names with underscores, the same as Loki
+// does for extracted labels.
+func sanitizeLabelName(name string) string {
+	sanitized := []byte(name)
+
+	for i := range sanitized {
+		if !isNameByte(sanitized[i], i == 0) {
+			sanitized[i] = '_'
+		}
+	}
+
+	return string(sanitized)
+}
+
+// isNumberByte reports whether the byte may be part of a number.
+func isNumberByte(char byte) bool {
+	return (char >= '0' && char <= '9') || char == '.' || char == '-' || char == '+' || char == 'e' || char == 'E'
+}
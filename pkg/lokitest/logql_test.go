@@ -0,0 +1,164 @@
+package lokitest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:funlen // Most of the function is test cases, no need to worry about length.
+func TestQuery_Evaluate(t *testing.T) {
+	t.Parallel()
+
+	streams := []push.Stream{
+		{
+			Labels: `{app="api", level="info"}`,
+			Entries: []push.Entry{
+				{Line: `{"status": 200, "msg": "ok", "request": {"path": "/"}}`},
+				{Line: `{"status": 503, "msg": "unavailable", "app": "proxy"}`},
+				{Line: "not json", StructuredMetadata: push.LabelsAdapter{{Name: "trace_id", Value: "abc"}}},
+			},
+		},
+		{
+			Labels: `{app="worker", level="error"}`,
+			Entries: []push.Entry{
+				{Line: `status=500 msg="job failed" duration=1.5`},
+				{Line: `status=404 msg=missing`},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "selector",
+			query:    `{app="worker"}`,
+			expected: []string{`status=500 msg="job failed" duration=1.5`, "status=404 msg=missing"},
+		},
+		{
+			name:     "contains",
+			query:    `{app="worker"} |= "failed"`,
+			expected: []string{`status=500 msg="job failed" duration=1.5`},
+		},
+		{
+			name:     "not-contains",
+			query:    `{app=~".+"} != "status"`,
+			expected: []string{"not json"},
+		},
+		{
+			name:     "regexp",
+			query:    `{app="api"} |~ "ok|json" !~ "^not"`,
+			expected: []string{`{"status": 200, "msg": "ok", "request": {"path": "/"}}`},
+		},
+		{
+			name:     "json",
+			query:    `{app="api"} | json | msg="ok" and request_path="/"`,
+			expected: []string{`{"status": 200, "msg": "ok", "request": {"path": "/"}}`},
+		},
+		{
+			name:     "json-extracted",
+			query:    `{app="api"} | json | app="api", app_extracted="proxy"`,
+			expected: []string{`{"status": 503, "msg": "unavailable", "app": "proxy"}`},
+		},
+		{
+			name:     "json-error",
+			query:    `{app="api"} | json | __error__="JSONParserErr"`,
+			expected: []string{"not json"},
+		},
+		{
+			name:     "logfmt",
+			query:    `{level="error"} | logfmt | msg=~"job.*"`,
+			expected: []string{`status=500 msg="job failed" duration=1.5`},
+		},
+		{
+			name:  "numeric",
+			query: `{app=~".+"} | json | logfmt | status >= 500`,
+			expected: []string{
+				`{"status": 503, "msg": "unavailable", "app": "proxy"}`,
+				`status=500 msg="job failed" duration=1.5`,
+			},
+		},
+		{
+			name:     "numeric-float",
+			query:    `{app="worker"} | logfmt | duration > 1 and status != 404`,
+			expected: []string{`status=500 msg="job failed" duration=1.5`},
+		},
+		{
+			name:     "numeric-not-string",
+			query:    `{app="worker"} | logfmt | status != "404"`,
+			expected: []string{`status=500 msg="job failed" duration=1.5`},
+		},
+		{
+			name:     "structured-metadata",
+			query:    `{app="api"} | trace_id="abc"`,
+			expected: []string{"not json"},
+		},
+		{
+			name:  "unsupported-stage",
+			query: `{app="api"} | pattern "<_>"`,
+			err:   true,
+		},
+		{
+			name:  "missing-value",
+			query: `{app="api"} |= `,
+			err:   true,
+		},
+		{
+			name:  "invalid-regexp",
+			query: `{app="api"} |~ "("`,
+			err:   true,
+		},
+		{
+			name:  "missing-pipe",
+			query: `{app="api"} json`,
+			err:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			query, err := ParseQuery(testCase.query)
+			if testCase.err {
+				require.ErrorIs(t, err, ErrInvalidQuery)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			lines := []string{}
+			for _, entry := range query.Evaluate(streams) {
+				lines = append(lines, entry.Line)
+			}
+
+			require.Equal(t, testCase.expected, lines)
+		})
+	}
+}
+
+func TestQuery_EvaluateLabels(t *testing.T) {
+	t.Parallel()
+
+	query, err := ParseQuery(`{app="api"} | json`)
+	require.NoError(t, err)
+
+	timestamp := time.Unix(1700000000, 0)
+	entries := query.Evaluate([]push.Stream{{
+		Labels:  `{app="api"}`,
+		Entries: []push.Entry{{Timestamp: timestamp, Line: `{"app": "proxy", "user": {"first-name": "a"}}`}},
+	}})
+
+	require.Equal(t, []Entry{{
+		Labels:    map[string]string{"app": "api", "app_extracted": "proxy", "user_first_name": "a"},
+		Timestamp: timestamp,
+		Line:      `{"app": "proxy", "user": {"first-name": "a"}}`,
+	}}, entries)
+}
@@ -0,0 +1,396 @@
+// Package lokitest provides a fake Loki server for testing code that sends logs to Loki, without having to run Loki
+// itself. It is used by the tests of this module and can be used the same way by the tests of its users.
+//
+// The [Server] accepts pushes to the Loki push API at [PushPath] and OTLP logs at [OTLPPath], in either binary protobuf
+// or JSON, storing all received entries in memory. Request bodies may be compressed using gzip, deflate, or zstd, as
+// indicated by the Content-Encoding header. OTLP log records are converted back to streams, with the resource
+// attributes and the severity text as labels and the log attributes as structured metadata.
+//
+// # Assertions
+//
+// Received entries can be selected using LogQL queries such as `{app="test", level=~"warn|error"} |= "timeout"`, see
+// [Server.Select], and checked using [AssertEntries] and [AssertLines]. Since pushes may happen asynchronously,
+// [Server.WaitForEntries] blocks until the expected number of entries has been received. Only a subset of LogQL is
+// supported, see [Query] for details. The same queries can be sent to the query endpoint at [QueryRangePath], e.g. by
+// tools under test that query Loki.
+//
+// # Failure injection
+//
+// By default, every push succeeds. [Server.Respond] scripts the responses to the next requests, such as errors with a
+// specific status code, body, latency, or Retry-After header. [Server.SetFailureRate] fails a random share of requests
+// and [Server.SetLatency] delays all of them.
+//
+// # Strict mode
+//
+// By default, the Server accepts any push it can decode. In strict mode, enabled using [Server.SetStrict], it validates
+// pushes the same way as Loki, rejecting invalid labels, lines that are too long, and out of order entries with Loki's
+// error messages. This catches entries that would only be rejected by a real Loki instance.
+package lokitest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+)
+
+// PushPath is the same as in the client package but provided here to avoid circular dependencies.
+const PushPath = "/loki/api/v1/push"
+
+// OTLPPath is the same as in the otlp package but provided here to avoid circular dependencies.
+const OTLPPath = "/otlp/v1/logs"
+
+// levelKey is the label that the severity text of OTLP log records is stored in.
+const levelKey = "level"
+
+// ErrTimeout is returned by [Server.WaitForEntries] when the entries are not received in time.
+var ErrTimeout = errors.New("timed out waiting for entries")
+
+// Entry is a single entry received by the [Server], together with the labels of its stream.
+type Entry struct {
+	Labels             map[string]string
+	Timestamp          time.Time
+	Line               string
+	StructuredMetadata map[string]string
+}
+
+// Response describes how the [Server] responds to a request. The zero value accepts the request.
+type Response struct {
+	// StatusCode is the status code of the response. If it is zero or a 2xx status, the pushed entries are stored and
+	// the status code defaults to 204 No Content. Otherwise, the request is rejected without storing any entries.
+	StatusCode int
+	// Body is the body of a rejected request. It defaults to the text of the status code.
+	Body string
+	// Latency is how long to wait before responding, in addition to the latency set using [Server.SetLatency].
+	Latency time.Duration
+	// RetryAfter is the value of the Retry-After header, rounded up to whole seconds. It is omitted if zero.
+	RetryAfter time.Duration
+}
+
+// accepted reports whether the response accepts the request.
+func (response Response) accepted() bool {
+	return response.StatusCode == 0 || (response.StatusCode >= 200 && response.StatusCode < 300)
+}
+
+// Server is a [http.Handler] that mocks the Loki push API. It stores all of the streams posted to it in memory. It can
+// safely handle multiple concurrent requests and all of its methods are safe to call concurrently.
+type Server struct {
+	lock     *sync.Mutex
+	streams  []push.Stream
+	requests int
+	// responses are the scripted responses to the next requests, consumed in order.
+	responses       []Response
+	latency         time.Duration
+	failureRate     float64
+	failureResponse Response
+	strict          bool
+	limits          Limits
+	// lastEntries are the last entries of each stream, used to reject out of order entries in strict mode.
+	lastEntries map[string]push.Entry
+	// received is closed and replaced whenever streams are stored, waking up WaitForEntries.
+	received chan struct{}
+}
+
+// NewServer creates a new Server that accepts every request.
+func NewServer() *Server {
+	return &Server{
+		lock:        &sync.Mutex{},
+		streams:     []push.Stream{},
+		limits:      DefaultLimits(),
+		lastEntries: make(map[string]push.Entry),
+		received:    make(chan struct{}),
+	}
+}
+
+// Start starts the server and returns a [httptest.Server] that can be used to get the URL of the server. It should not
+// be called multiple times.
+func (server *Server) Start() *httptest.Server {
+	return httptest.NewServer(server)
+}
+
+// StartUnix starts the server on a Unix domain socket at the given path, which must not exist yet. The URL of the
+// returned [httptest.Server] is the path prefixed with unix://, so it can be passed to the WithUnixSocket method of the
+// client, and the socket is removed when the server is closed. It should not be called multiple times.
+func (server *Server) StartUnix(socket string) (*httptest.Server, error) {
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	httpServer := httptest.NewUnstartedServer(server)
+	_ = httpServer.Listener.Close()
+	httpServer.Listener = listener
+	httpServer.Start()
+	httpServer.URL = "unix://" + socket
+
+	return httpServer, nil
+}
+
+// Respond scripts the responses to the next requests. Each request consumes one response, in order, before the server
+// falls back to accepting requests or failing them randomly, see [Server.SetFailureRate].
+func (server *Server) Respond(responses ...Response) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.responses = append(server.responses, responses...)
+}
+
+// FailNext makes the next count requests fail with a 500 Internal Server Error. It is a shorthand for [Server.Respond].
+func (server *Server) FailNext(count int) {
+	for range count {
+		server.Respond(Response{StatusCode: http.StatusInternalServerError})
+	}
+}
+
+// SetFailureRate makes the given share of requests, between 0 and 1, fail randomly with the given response. Scripted
+// responses take precedence. A rate of zero disables random failures.
+func (server *Server) SetFailureRate(rate float64, response Response) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.failureRate = rate
+	server.failureResponse = response
+}
+
+// SetLatency delays the response to every request by the given duration.
+func (server *Server) SetLatency(latency time.Duration) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.latency = latency
+}
+
+// Reset removes all received streams and restores the default behavior of accepting every request without latency or
+// strict mode.
+func (server *Server) Reset() {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.streams = []push.Stream{}
+	server.requests = 0
+	server.responses = nil
+	server.latency = 0
+	server.failureRate = 0
+	server.failureResponse = Response{}
+	server.strict = false
+	server.limits = DefaultLimits()
+	server.lastEntries = make(map[string]push.Entry)
+}
+
+// Requests returns the number of requests to the push endpoints so far, including rejected ones.
+func (server *Server) Requests() int {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	return server.requests
+}
+
+// Streams returns a copy of the streams that have been posted to the server, in the order they were received.
+func (server *Server) Streams() []push.Stream {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	return slices.Clone(server.streams)
+}
+
+// Entries returns all entries that have been posted to the server, in the order they were received. Streams with labels
+// that cannot be parsed are skipped.
+func (server *Server) Entries() []Entry {
+	entries, _ := server.Select("{}")
+
+	return entries
+}
+
+// Select returns the entries matching the LogQL query, in the order they were received. The query is usually just a
+// stream selector, but may contain any of the stages supported by [Query]. It returns an error wrapping
+// [ErrInvalidQuery] if the query cannot be parsed.
+func (server *Server) Select(query string) ([]Entry, error) {
+	parsed, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed.Evaluate(server.Streams()), nil
+}
+
+// WaitForEntries blocks until at least count entries matching the LogQL query have been received or the timeout
+// expires. It returns the matching entries, or an error wrapping [ErrTimeout] along with the entries received so far.
+func (server *Server) WaitForEntries(query string, count int, timeout time.Duration) ([]Entry, error) {
+	parsed, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		server.lock.Lock()
+		entries := parsed.Evaluate(server.streams)
+		received := server.received
+		server.lock.Unlock()
+
+		if len(entries) >= count {
+			return entries, nil
+		}
+
+		select {
+		case <-received:
+		case <-timer.C:
+			return entries, fmt.Errorf("%w: received %d of %d entries matching %s", ErrTimeout, len(entries), count,
+				query)
+		}
+	}
+}
+
+var _ http.Handler = (*Server)(nil)
+
+func (server *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path == QueryRangePath {
+		server.serveQuery(writer, request)
+
+		return
+	}
+
+	if request.URL.Path != PushPath && request.URL.Path != OTLPPath {
+		writer.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	if request.Method != http.MethodPost {
+		writer.Header().Add("Allow", http.MethodPost)
+		writeError(writer, http.StatusMethodNotAllowed, "Method Not Allowed")
+
+		return
+	}
+
+	response, latency := server.nextResponse()
+
+	select {
+	case <-time.After(latency):
+	case <-request.Context().Done():
+		return
+	}
+
+	if !response.accepted() {
+		writeResponse(writer, response)
+
+		return
+	}
+
+	body, err := decodeContent(request.Header.Get("Content-Encoding"), request.Body)
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	var streams []push.Stream
+	if request.URL.Path == OTLPPath {
+		streams, err = decodeOTLP(request.Header.Get("Content-Type"), body)
+	} else {
+		streams, err = decodePush(body)
+	}
+
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if errs := server.store(streams); len(errs) > 0 {
+		writeError(writer, http.StatusBadRequest, strings.Join(errs, "\n"))
+
+		return
+	}
+
+	if response.StatusCode == 0 {
+		response.StatusCode = http.StatusNoContent
+	}
+
+	writer.WriteHeader(response.StatusCode)
+}
+
+// nextResponse counts the request and returns the response to it along with the total latency.
+func (server *Server) nextResponse() (Response, time.Duration) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.requests++
+
+	response := Response{}
+
+	switch {
+	case len(server.responses) > 0:
+		response = server.responses[0]
+		server.responses = server.responses[1:]
+	case server.failureRate > 0 && rand.Float64() < server.failureRate: //nolint:gosec // No need for secure randomness.
+		response = server.failureResponse
+	}
+
+	return response, server.latency + response.Latency
+}
+
+// store adds the streams to the server and wakes up any waiting callers. In strict mode, only the valid streams are
+// added and the errors for the invalid ones are returned.
+func (server *Server) store(streams []push.Stream) []string {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	var errs []string
+	if server.strict {
+		streams, errs = server.validateStreams(streams)
+	}
+
+	server.streams = append(server.streams, streams...)
+
+	close(server.received)
+	server.received = make(chan struct{})
+
+	return errs
+}
+
+// labelsAdapterToMap converts structured metadata to a map, returning nil if there is none.
+func labelsAdapterToMap(labelsAdapter push.LabelsAdapter) map[string]string {
+	if len(labelsAdapter) == 0 {
+		return nil
+	}
+
+	values := make(map[string]string, len(labelsAdapter))
+	for _, label := range labelsAdapter {
+		values[label.Name] = label.Value
+	}
+
+	return values
+}
+
+// writeResponse writes the response of a rejected request.
+func writeResponse(writer http.ResponseWriter, response Response) {
+	if response.RetryAfter > 0 {
+		writer.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(response.RetryAfter.Seconds()))))
+	}
+
+	body := response.Body
+	if body == "" {
+		body = http.StatusText(response.StatusCode)
+	}
+
+	writeError(writer, response.StatusCode, body)
+}
+
+func writeError(writer http.ResponseWriter, statusCode int, message string) {
+	writer.Header().Add("Content-Type", "text/plain")
+	writer.WriteHeader(statusCode)
+	_, _ = writer.Write([]byte(message))
+}
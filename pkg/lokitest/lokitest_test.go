@@ -0,0 +1,441 @@
+package lokitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/flate"
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+)
+
+// pushLine pushes an entry with the given labels and line to the server, returning the error of the push.
+func pushLine(t *testing.T, url string, labels client.LabelMap, line string) error {
+	t.Helper()
+
+	return client.NewLokiClient(url+PushPath).Push(t.Context(), client.Entry{
+		Timestamp: time.Now(),
+		Labels:    labels,
+		Line:      line,
+	})
+}
+
+func TestServer_Select(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer()
+	httpServer := server.Start()
+
+	defer httpServer.Close()
+
+	require.NoError(t, pushLine(t, httpServer.URL, client.LabelMap{"app": "test", "level": "info"}, "first"))
+	require.NoError(t, pushLine(t, httpServer.URL, client.LabelMap{"app": "test", "level": "error"}, "second"))
+	require.NoError(t, pushLine(t, httpServer.URL, client.LabelMap{"app": "other", "level": "warn"}, "third"))
+
+	AssertLines(t, server, `{app="test"}`, "first", "second")
+	AssertLines(t, server, `{level=~"warn|error"}`, "second", "third")
+	AssertLines(t, server, `{app!="test", level!~"info"}`, "third")
+	AssertLines(t, server, `{app="missing"}`)
+	AssertLines(t, server, `{}`, "first", "second", "third")
+
+	AssertEntries(t, server, `{app="other"}`, Entry{
+		Labels: map[string]string{"app": "other", "level": "warn"},
+		Line:   "third",
+	})
+
+	_, err := server.Select(`{app=}`)
+	require.ErrorIs(t, err, ErrInvalidQuery)
+
+	require.Len(t, server.Entries(), 3)
+	require.Len(t, server.Streams(), 3)
+	require.Equal(t, 3, server.Requests())
+}
+
+func TestServer_WaitForEntries(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer()
+	httpServer := server.Start()
+
+	defer httpServer.Close()
+
+	go func() {
+		for range 3 {
+			time.Sleep(10 * time.Millisecond)
+
+			_ = pushLine(t, httpServer.URL, client.LabelMap{"app": "test"}, "line")
+		}
+	}()
+
+	entries, err := server.WaitForEntries(`{app="test"}`, 3, time.Second)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	entries, err = server.WaitForEntries(`{app="test"}`, 4, 50*time.Millisecond)
+	require.ErrorIs(t, err, ErrTimeout)
+	require.Len(t, entries, 3)
+
+	_, err = server.WaitForEntries(`invalid`, 1, time.Second)
+	require.ErrorIs(t, err, ErrInvalidQuery)
+}
+
+//nolint:funlen // Most of the function is test cases, no need to worry about length.
+func TestServer_Respond(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		response       Response
+		expectedStatus int
+		expectedBody   string
+		stored         bool
+	}{
+		{
+			name:     "accepted",
+			response: Response{},
+			stored:   true,
+		},
+		{
+			name:     "accepted-with-status",
+			response: Response{StatusCode: http.StatusOK},
+			stored:   true,
+		},
+		{
+			name:           "default-body",
+			response:       Response{StatusCode: http.StatusServiceUnavailable},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "Service Unavailable",
+		},
+		{
+			name: "retry-after",
+			response: Response{
+				StatusCode: http.StatusTooManyRequests,
+				Body:       "Ingestion rate limit exceeded",
+				RetryAfter: 1500 * time.Millisecond,
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   "Ingestion rate limit exceeded",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := NewServer()
+			httpServer := server.Start()
+
+			defer httpServer.Close()
+
+			server.Respond(testCase.response)
+
+			err := pushLine(t, httpServer.URL, client.LabelMap{"app": "test"}, "line")
+			if testCase.stored {
+				require.NoError(t, err)
+				require.Len(t, server.Entries(), 1)
+
+				return
+			}
+
+			var statusErr *client.PushStatusError
+			require.ErrorAs(t, err, &statusErr)
+			require.Equal(t, testCase.expectedStatus, statusErr.StatusCode)
+			require.Equal(t, testCase.expectedBody, string(statusErr.Body))
+			require.Empty(t, server.Entries())
+
+			// The scripted response is consumed, so the next push succeeds.
+			require.NoError(t, pushLine(t, httpServer.URL, client.LabelMap{"app": "test"}, "line"))
+		})
+	}
+}
+
+func TestServer_RetryAfterHeader(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer()
+	httpServer := server.Start()
+
+	defer httpServer.Close()
+
+	server.Respond(Response{StatusCode: http.StatusTooManyRequests, RetryAfter: 1500 * time.Millisecond})
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, httpServer.URL+PushPath, http.NoBody)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, "2", resp.Header.Get("Retry-After"))
+}
+
+func TestServer_StartUnix(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer()
+	socket := filepath.Join(t.TempDir(), "loki.sock")
+
+	httpServer, err := server.StartUnix(socket)
+	require.NoError(t, err)
+
+	defer httpServer.Close()
+
+	require.Equal(t, "unix://"+socket, httpServer.URL)
+
+	// The host of the URL is only used for the Host header.
+	lokiClient := client.NewLokiClient("http://loki" + PushPath).WithUnixSocket(httpServer.URL)
+	require.NoError(t, lokiClient.Push(t.Context(), client.Entry{
+		Timestamp: time.Now(),
+		Labels:    client.LabelMap{"app": "test"},
+		Line:      "over a socket",
+	}))
+
+	AssertLines(t, server, `{app="test"}`, "over a socket")
+
+	// The socket is already in use.
+	_, err = NewServer().StartUnix(socket)
+	require.Error(t, err)
+}
+
+func TestServer_FailureRateAndLatency(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer()
+	httpServer := server.Start()
+
+	defer httpServer.Close()
+
+	server.SetFailureRate(1, Response{StatusCode: http.StatusBadGateway})
+	require.ErrorIs(t, pushLine(t, httpServer.URL, client.LabelMap{"app": "test"}, "line"), &client.PushStatusError{})
+
+	server.SetFailureRate(0, Response{})
+	server.SetLatency(50 * time.Millisecond)
+
+	start := time.Now()
+
+	require.NoError(t, pushLine(t, httpServer.URL, client.LabelMap{"app": "test"}, "line"))
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	server.FailNext(1)
+	server.Reset()
+
+	require.Empty(t, server.Entries())
+	require.Zero(t, server.Requests())
+
+	start = time.Now()
+
+	require.NoError(t, pushLine(t, httpServer.URL, client.LabelMap{"app": "test"}, "line"))
+	require.Less(t, time.Since(start), 50*time.Millisecond, "Expected latency to be reset")
+	require.Len(t, server.Entries(), 1)
+}
+
+func TestServer_InvalidRequests(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{name: "not-found", method: http.MethodPost, path: "/invalid", expectedStatus: http.StatusNotFound},
+		{name: "method", method: http.MethodGet, path: PushPath, expectedStatus: http.StatusMethodNotAllowed},
+		{name: "body", method: http.MethodPost, path: PushPath, expectedStatus: http.StatusBadRequest},
+		{name: "query-method", method: http.MethodPut, path: QueryRangePath, expectedStatus: http.StatusMethodNotAllowed},
+		{name: "query", method: http.MethodGet, path: QueryRangePath, expectedStatus: http.StatusBadRequest},
+		{
+			name:           "query-limit",
+			method:         http.MethodGet,
+			path:           QueryRangePath + "?query=%7B%7D&limit=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			httpServer := NewServer().Start()
+			defer httpServer.Close()
+
+			req, err := http.NewRequestWithContext(t.Context(), testCase.method, httpServer.URL+testCase.path,
+				http.NoBody)
+			require.NoError(t, err)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, testCase.expectedStatus, resp.StatusCode)
+		})
+	}
+}
+
+//nolint:funlen // Most of the function is test cases, no need to worry about length.
+func TestServer_QueryRange(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer()
+	httpServer := server.Start()
+
+	// The subtests run in parallel after the test function returns, so the server must outlive it.
+	t.Cleanup(httpServer.Close)
+
+	lokiClient := client.NewLokiClient(httpServer.URL + PushPath)
+
+	for i, line := range []string{"first", "second", "third"} {
+		require.NoError(t, lokiClient.Push(t.Context(), client.Entry{
+			Timestamp:          time.Unix(int64(1700000000+i), 0),
+			Labels:             client.LabelMap{"app": "test"},
+			Line:               line,
+			StructuredMetadata: map[string]string{"index": strconv.Itoa(i % 2)},
+		}))
+	}
+
+	testCases := []struct {
+		name     string
+		params   url.Values
+		expected []queryStream
+	}{
+		{
+			name:   "backward",
+			params: url.Values{"query": {`{app="test"} != "second"`}},
+			expected: []queryStream{{
+				Stream: map[string]string{"app": "test", "index": "0"},
+				Values: [][2]string{{"1700000002000000000", "third"}, {"1700000000000000000", "first"}},
+			}},
+		},
+		{
+			name:   "forward-limit",
+			params: url.Values{"query": {`{app="test"}`}, "direction": {"forward"}, "limit": {"2"}},
+			expected: []queryStream{
+				{
+					Stream: map[string]string{"app": "test", "index": "0"},
+					Values: [][2]string{{"1700000000000000000", "first"}},
+				},
+				{
+					Stream: map[string]string{"app": "test", "index": "1"},
+					Values: [][2]string{{"1700000001000000000", "second"}},
+				},
+			},
+		},
+		{
+			name: "time-range",
+			params: url.Values{
+				"query": {`{app="test"}`},
+				"start": {"1700000000.5"},
+				"end":   {"2023-11-14T22:13:22Z"},
+			},
+			expected: []queryStream{{
+				Stream: map[string]string{"app": "test", "index": "1"},
+				Values: [][2]string{{"1700000001000000000", "second"}},
+			}},
+		},
+		{
+			name:     "no-match",
+			params:   url.Values{"query": {`{app="test"}`}, "end": {"1700000000000000000"}},
+			expected: []queryStream{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodGet,
+				httpServer.URL+QueryRangePath+"?"+testCase.params.Encode(), http.NoBody)
+			require.NoError(t, err)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+
+			defer resp.Body.Close()
+
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var response queryResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+			require.Equal(t, "success", response.Status)
+			require.Equal(t, "streams", response.Data.ResultType)
+			require.Equal(t, testCase.expected, response.Data.Result)
+		})
+	}
+}
+
+func TestServer_ContentEncoding(t *testing.T) {
+	t.Parallel()
+
+	entry := client.Entry{Timestamp: time.Now(), Labels: client.LabelMap{"app": "test"}, Line: "line"}
+
+	encoded, err := entry.Encode()
+	require.NoError(t, err)
+
+	var deflated bytes.Buffer
+
+	deflateWriter, err := flate.NewWriter(&deflated, flate.DefaultCompression)
+	require.NoError(t, err)
+
+	_, err = deflateWriter.Write(encoded)
+	require.NoError(t, err)
+	require.NoError(t, deflateWriter.Close())
+
+	testCases := []struct {
+		name            string
+		contentEncoding string
+		body            []byte
+		expectedStatus  int
+		expectedBody    string
+	}{
+		{name: "deflate", contentEncoding: "deflate", body: deflated.Bytes(), expectedStatus: http.StatusNoContent},
+		{
+			name:            "invalid-gzip",
+			contentEncoding: "gzip",
+			body:            encoded,
+			expectedStatus:  http.StatusBadRequest,
+			expectedBody:    "failed to decode request body",
+		},
+		{
+			name:            "unsupported",
+			contentEncoding: "br",
+			body:            encoded,
+			expectedStatus:  http.StatusBadRequest,
+			expectedBody:    `Content-Encoding "br" not supported`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := NewServer()
+			httpServer := server.Start()
+
+			defer httpServer.Close()
+
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, httpServer.URL+PushPath,
+				bytes.NewReader(testCase.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Encoding", testCase.contentEncoding)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectedStatus, resp.StatusCode)
+			require.Equal(t, testCase.expectedBody, string(body))
+
+			if testCase.expectedStatus == http.StatusNoContent {
+				AssertLines(t, server, `{app="test"}`, "line")
+			}
+		})
+	}
+}
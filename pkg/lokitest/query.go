@@ -0,0 +1,170 @@
+package lokitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tslnc04/loki-logger/pkg/internal/labels"
+)
+
+// QueryRangePath is the path of the Loki query endpoint served by the [Server].
+const QueryRangePath = "/loki/api/v1/query_range"
+
+// defaultQueryLimit is the default maximum number of entries returned by the query endpoint, the same as in Loki.
+const defaultQueryLimit = 100
+
+// queryResponse is the JSON response of the query endpoint for log queries.
+type queryResponse struct {
+	Status string    `json:"status"`
+	Data   queryData `json:"data"`
+}
+
+// queryData is the data of a [queryResponse].
+type queryData struct {
+	ResultType string        `json:"resultType"`
+	Result     []queryStream `json:"result"`
+}
+
+// queryStream is a single stream of a [queryData], with the values given as pairs of timestamps in nanoseconds and
+// lines.
+type queryStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// serveQuery handles a request to the query endpoint. It supports the query, start, end, limit, and direction
+// parameters. Unlike Loki, the time range is unbounded by default, so that entries with any timestamp are found.
+func (server *Server) serveQuery(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet && request.Method != http.MethodPost {
+		writer.Header().Add("Allow", http.MethodGet+", "+http.MethodPost)
+		writeError(writer, http.StatusMethodNotAllowed, "Method Not Allowed")
+
+		return
+	}
+
+	entries, err := server.queryEntries(request)
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(writer).Encode(queryResponse{
+		Status: "success",
+		Data: queryData{
+			ResultType: "streams",
+			Result:     groupStreams(entries),
+		},
+	})
+}
+
+// queryEntries evaluates the query of the request and returns the entries within its time range, sorted by the
+// direction and limited to its limit.
+func (server *Server) queryEntries(request *http.Request) ([]Entry, error) {
+	query, err := ParseQuery(request.FormValue("query"))
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := parseTimestamp(request.FormValue("start"), time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+
+	end, err := parseTimestamp(request.FormValue("end"), time.Unix(0, math.MaxInt64))
+	if err != nil {
+		return nil, fmt.Errorf("invalid end: %w", err)
+	}
+
+	limit := defaultQueryLimit
+	if value := request.FormValue("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limit %q", value)
+		}
+	}
+
+	direction := request.FormValue("direction")
+	if direction != "" && direction != "forward" && direction != "backward" {
+		return nil, fmt.Errorf("invalid direction %q", direction)
+	}
+
+	entries := slices.DeleteFunc(query.Evaluate(server.Streams()), func(entry Entry) bool {
+		return entry.Timestamp.Before(start) || !entry.Timestamp.Before(end)
+	})
+
+	slices.SortStableFunc(entries, func(left, right Entry) int {
+		if direction == "forward" {
+			return left.Timestamp.Compare(right.Timestamp)
+		}
+
+		return right.Timestamp.Compare(left.Timestamp)
+	})
+
+	return entries[:min(limit, len(entries))], nil
+}
+
+// groupStreams groups the entries by their labels, including their structured metadata like Loki does, keeping the
+// order of the entries within each stream.
+func groupStreams(entries []Entry) []queryStream {
+	streams := []queryStream{}
+	indices := make(map[string]int)
+
+	for _, entry := range entries {
+		streamLabels := maps.Clone(entry.StructuredMetadata)
+		if streamLabels == nil {
+			streamLabels = make(map[string]string, len(entry.Labels))
+		}
+
+		maps.Copy(streamLabels, entry.Labels)
+
+		key := labels.Format(streamLabels)
+
+		index, ok := indices[key]
+		if !ok {
+			index = len(streams)
+			indices[key] = index
+			streams = append(streams, queryStream{Stream: streamLabels})
+		}
+
+		streams[index].Values = append(streams[index].Values,
+			[2]string{strconv.FormatInt(entry.Timestamp.UnixNano(), 10), entry.Line})
+	}
+
+	return streams
+}
+
+// parseTimestamp parses a timestamp the same way as Loki: as seconds if it contains a decimal point or has at most 10
+// digits, as nanoseconds if it is a longer integer, and as RFC 3339 otherwise. An empty value results in the default.
+func parseTimestamp(value string, defaultTime time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultTime, nil
+	}
+
+	if strings.Contains(value, ".") {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			whole, fraction := math.Modf(seconds)
+
+			return time.Unix(int64(whole), int64(fraction*float64(time.Second))), nil
+		}
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Parse(time.RFC3339Nano, value)
+	}
+
+	if len(value) <= 10 {
+		return time.Unix(number, 0), nil
+	}
+
+	return time.Unix(0, number), nil
+}
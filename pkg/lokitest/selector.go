@@ -0,0 +1,288 @@
+package lokitest
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSelector is returned when a stream selector cannot be parsed.
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// MatchType is the operator of a [Matcher].
+type MatchType int
+
+const (
+	// MatchEqual matches labels equal to the value, written as `=`.
+	MatchEqual MatchType = iota
+	// MatchNotEqual matches labels not equal to the value, written as `!=`.
+	MatchNotEqual
+	// MatchRegexp matches labels fully matching the regular expression, written as `=~`.
+	MatchRegexp
+	// MatchNotRegexp matches labels not fully matching the regular expression, written as `!~`.
+	MatchNotRegexp
+)
+
+// String returns the LogQL operator of the MatchType.
+func (matchType MatchType) String() string {
+	switch matchType {
+	case MatchEqual:
+		return "="
+	case MatchNotEqual:
+		return "!="
+	case MatchRegexp:
+		return "=~"
+	case MatchNotRegexp:
+		return "!~"
+	default:
+		return "MatchType(" + strconv.Itoa(int(matchType)) + ")"
+	}
+}
+
+// Matcher matches the value of a single label. A missing label is treated as an empty value, the same as in Loki.
+type Matcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+	// regexp is the compiled, fully anchored Value for the regular expression match types.
+	regexp *regexp.Regexp
+}
+
+// NewMatcher creates a new Matcher, compiling the value for the regular expression match types.
+func NewMatcher(matchType MatchType, name, value string) (*Matcher, error) {
+	matcher := &Matcher{Name: name, Type: matchType, Value: value}
+
+	if matchType == MatchRegexp || matchType == MatchNotRegexp {
+		compiled, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidSelector, err)
+		}
+
+		matcher.regexp = compiled
+	}
+
+	return matcher, nil
+}
+
+// Matches reports whether the value matches.
+func (matcher *Matcher) Matches(value string) bool {
+	switch matcher.Type {
+	case MatchEqual:
+		return value == matcher.Value
+	case MatchNotEqual:
+		return value != matcher.Value
+	case MatchRegexp:
+		return matcher.regexp.MatchString(value)
+	case MatchNotRegexp:
+		return !matcher.regexp.MatchString(value)
+	default:
+		return false
+	}
+}
+
+// String returns the Matcher in LogQL syntax.
+func (matcher *Matcher) String() string {
+	return matcher.Name + matcher.Type.String() + strconv.Quote(matcher.Value)
+}
+
+// Selector is a LogQL stream selector, such as `{app="test", level=~"warn|error"}`. It matches label sets for which
+// all of its matchers match. An empty selector matches everything.
+type Selector []*Matcher
+
+// ParseSelector parses a LogQL stream selector. Values may be quoted using double quotes or backticks.
+func ParseSelector(input string) (Selector, error) {
+	scanner := &scanner{input: input}
+
+	selector, err := scanner.selector()
+	if err != nil {
+		return nil, err
+	}
+
+	if !scanner.done() {
+		return nil, scanner.errorf("unexpected %q after selector", scanner.rest())
+	}
+
+	return selector, nil
+}
+
+// Matches reports whether all matchers of the Selector match the labels.
+func (selector Selector) Matches(labels map[string]string) bool {
+	for _, matcher := range selector {
+		if !matcher.Matches(labels[matcher.Name]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns the Selector in LogQL syntax.
+func (selector Selector) String() string {
+	matchers := make([]string, 0, len(selector))
+	for _, matcher := range selector {
+		matchers = append(matchers, matcher.String())
+	}
+
+	return "{" + strings.Join(matchers, ", ") + "}"
+}
+
+// scanner is a minimal scanner for the LogQL syntax understood by this package. Whitespace between tokens is skipped.
+type scanner struct {
+	input string
+	pos   int
+	// sentinel is the error wrapped by all errors of the scanner. It defaults to ErrInvalidSelector.
+	sentinel error
+}
+
+// selector parses a stream selector at the current position.
+func (scanner *scanner) selector() (Selector, error) {
+	if !scanner.consume("{") {
+		return nil, scanner.errorf("expected '{'")
+	}
+
+	selector := Selector{}
+
+	for !scanner.consume("}") {
+		if len(selector) > 0 && !scanner.consume(",") {
+			return nil, scanner.errorf("expected ',' or '}'")
+		}
+
+		matcher, err := scanner.matcher()
+		if err != nil {
+			return nil, err
+		}
+
+		selector = append(selector, matcher)
+	}
+
+	return selector, nil
+}
+
+// matcher parses a single label matcher at the current position.
+func (scanner *scanner) matcher() (*Matcher, error) {
+	name, ok := scanner.identifier()
+	if !ok {
+		return nil, scanner.errorf("expected label name")
+	}
+
+	matchType, ok := scanner.matchType()
+	if !ok {
+		return nil, scanner.errorf("expected operator after label %q", name)
+	}
+
+	value, err := scanner.quoted()
+	if err != nil {
+		return nil, err
+	}
+
+	matcher, err := NewMatcher(matchType, name, value)
+	if err != nil {
+		return nil, scanner.errorf("invalid regular expression %q for label %q", value, name)
+	}
+
+	return matcher, nil
+}
+
+// matchType parses a label matching operator at the current position.
+func (scanner *scanner) matchType() (MatchType, bool) {
+	switch {
+	case scanner.consume("=~"):
+		return MatchRegexp, true
+	case scanner.consume("!~"):
+		return MatchNotRegexp, true
+	case scanner.consume("!="):
+		return MatchNotEqual, true
+	case scanner.consume("="):
+		return MatchEqual, true
+	default:
+		return MatchEqual, false
+	}
+}
+
+// skipSpace advances past any whitespace.
+func (scanner *scanner) skipSpace() {
+	for scanner.pos < len(scanner.input) && strings.ContainsRune(" \t\r\n", rune(scanner.input[scanner.pos])) {
+		scanner.pos++
+	}
+}
+
+// consume advances past the token if the input continues with it.
+func (scanner *scanner) consume(token string) bool {
+	scanner.skipSpace()
+
+	if strings.HasPrefix(scanner.input[scanner.pos:], token) {
+		scanner.pos += len(token)
+
+		return true
+	}
+
+	return false
+}
+
+// identifier parses a label name, i.e. a letter or underscore followed by letters, digits, and underscores.
+func (scanner *scanner) identifier() (string, bool) {
+	scanner.skipSpace()
+
+	start := scanner.pos
+
+	for scanner.pos < len(scanner.input) && isNameByte(scanner.input[scanner.pos], scanner.pos == start) {
+		scanner.pos++
+	}
+
+	return scanner.input[start:scanner.pos], scanner.pos > start
+}
+
+// quoted parses a string quoted using double quotes or backticks.
+func (scanner *scanner) quoted() (string, error) {
+	scanner.skipSpace()
+
+	rest := scanner.rest()
+	if rest == "" || (rest[0] != '"' && rest[0] != '`') {
+		return "", scanner.errorf("expected quoted string")
+	}
+
+	quoted, err := strconv.QuotedPrefix(rest)
+	if err != nil {
+		return "", scanner.errorf("invalid quoted string")
+	}
+
+	value, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", scanner.errorf("invalid quoted string")
+	}
+
+	scanner.pos += len(quoted)
+
+	return value, nil
+}
+
+// rest returns the remaining input.
+func (scanner *scanner) rest() string {
+	return scanner.input[scanner.pos:]
+}
+
+// done reports whether only whitespace is left.
+func (scanner *scanner) done() bool {
+	scanner.skipSpace()
+
+	return scanner.pos == len(scanner.input)
+}
+
+// errorf returns an error wrapping the sentinel of the scanner that includes the position in the input.
+func (scanner *scanner) errorf(format string, args ...any) error {
+	sentinel := scanner.sentinel
+	if sentinel == nil {
+		sentinel = ErrInvalidSelector
+	}
+
+	return fmt.Errorf("%w: %s at position %d in %q", sentinel, fmt.Sprintf(format, args...), scanner.pos,
+		scanner.input)
+}
+
+// isNameByte reports whether the byte is allowed in a label name, where digits are not allowed as the first byte.
+func isNameByte(char byte, first bool) bool {
+	return char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
+		(!first && char >= '0' && char <= '9')
+}
@@ -0,0 +1,100 @@
+package lokitest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:funlen // Most of the function is test cases, no need to worry about length.
+func TestParseSelector(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{"app": "test", "level": "error"}
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+		matches  bool
+		err      bool
+	}{
+		{
+			name:     "empty",
+			input:    "{}",
+			expected: "{}",
+			matches:  true,
+		},
+		{
+			name:     "equal",
+			input:    ` { app = "test" } `,
+			expected: `{app="test"}`,
+			matches:  true,
+		},
+		{
+			name:     "all-operators",
+			input:    "{app=`test`, level!=\"info\", level=~\"warn|error\", missing!~\".+\"}",
+			expected: `{app="test", level!="info", level=~"warn|error", missing!~".+"}`,
+			matches:  true,
+		},
+		{
+			name:     "anchored-regexp",
+			input:    `{app=~"tes"}`,
+			expected: `{app=~"tes"}`,
+			matches:  false,
+		},
+		{
+			name:     "escaped",
+			input:    `{app="te\"st"}`,
+			expected: `{app="te\"st"}`,
+			matches:  false,
+		},
+		{
+			name:  "missing-brace",
+			input: `app="test"`,
+			err:   true,
+		},
+		{
+			name:  "missing-comma",
+			input: `{app="test" level="error"}`,
+			err:   true,
+		},
+		{
+			name:  "missing-operator",
+			input: `{app}`,
+			err:   true,
+		},
+		{
+			name:  "unquoted",
+			input: `{app=test}`,
+			err:   true,
+		},
+		{
+			name:  "invalid-regexp",
+			input: `{app=~"("}`,
+			err:   true,
+		},
+		{
+			name:  "trailing",
+			input: `{app="test"} |= "line"`,
+			err:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			selector, err := ParseSelector(testCase.input)
+			if testCase.err {
+				require.ErrorIs(t, err, ErrInvalidSelector)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, selector.String())
+			require.Equal(t, testCase.matches, selector.Matches(labels))
+		})
+	}
+}
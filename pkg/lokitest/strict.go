@@ -0,0 +1,196 @@
+package lokitest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/tslnc04/loki-logger/pkg/internal/labels"
+)
+
+// tenant is the tenant reported in the error messages of the strict mode, since the Server does not support multiple
+// tenants.
+const tenant = "fake"
+
+// Limits are the limits enforced by the [Server] in strict mode, see [Server.SetStrict]. A limit of zero or less
+// disables the corresponding check. Sizes and lengths are measured in bytes.
+type Limits struct {
+	// MaxLineSize is the maximum size of a line, like max_line_size in Loki.
+	MaxLineSize int
+	// MaxLabelNamesPerSeries is the maximum number of labels, like max_label_names_per_series in Loki.
+	MaxLabelNamesPerSeries int
+	// MaxLabelNameLength is the maximum length of a label name, like max_label_name_length in Loki.
+	MaxLabelNameLength int
+	// MaxLabelValueLength is the maximum length of a label value, like max_label_value_length in Loki.
+	MaxLabelValueLength int
+}
+
+// DefaultLimits returns the default limits of Loki.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxLineSize:            256 << 10,
+		MaxLabelNamesPerSeries: 15,
+		MaxLabelNameLength:     1024,
+		MaxLabelValueLength:    2048,
+	}
+}
+
+// SetStrict enables or disables the strict mode of the server. In strict mode, pushes are validated the same way as by
+// Loki, with the same status codes and error messages:
+//
+//   - Labels must follow the LogQL syntax, e.g. label names must only contain letters, digits, and underscores, and
+//     must not be repeated. At least one label is required.
+//   - The number of labels, the length of label names and values, and the size of lines must not exceed the [Limits].
+//   - Entries must be pushed in order for each stream, like in Loki with unordered writes disabled. An entry with the
+//     same timestamp and line as the previous entry of its stream is silently ignored.
+//
+// Like in Loki, valid streams of a push are stored even if other streams are rejected. Rejected pushes result in a
+// 400 Bad Request with one line per error. Stream labels are stored in their normalized form, i.e. sorted by name.
+func (server *Server) SetStrict(strict bool) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.strict = strict
+}
+
+// SetLimits sets the limits enforced in strict mode. It defaults to [DefaultLimits].
+func (server *Server) SetLimits(limits Limits) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.limits = limits
+}
+
+// validateStreams returns the streams accepted in strict mode and the errors for the rejected entries. It must be
+// called with the lock held, since it tracks the last entry of each stream.
+func (server *Server) validateStreams(streams []push.Stream) ([]push.Stream, []string) {
+	accepted := make([]push.Stream, 0, len(streams))
+
+	var errs []string
+
+	for _, stream := range streams {
+		streamLabels, err := server.limits.validateLabels(stream.Labels)
+		if err != nil {
+			errs = append(errs, err.Error())
+
+			continue
+		}
+
+		stream.Labels = streamLabels
+
+		entries, streamErrs := server.validateEntries(stream)
+		errs = append(errs, streamErrs...)
+
+		if len(entries) > 0 {
+			stream.Entries = entries
+			accepted = append(accepted, stream)
+		}
+	}
+
+	return accepted, errs
+}
+
+// validateEntries returns the entries of the stream that are accepted and the errors for the rejected ones.
+func (server *Server) validateEntries(stream push.Stream) ([]push.Entry, []string) {
+	entries := make([]push.Entry, 0, len(stream.Entries))
+
+	var (
+		errs    []string
+		ignored []string
+	)
+
+	for _, entry := range stream.Entries {
+		if exceeds(len(entry.Line), server.limits.MaxLineSize) {
+			errs = append(errs, fmt.Sprintf(
+				"Max entry size '%d' bytes exceeded for stream '%s' while adding an entry with length '%d' bytes",
+				server.limits.MaxLineSize, stream.Labels, len(entry.Line)))
+
+			continue
+		}
+
+		last, ok := server.lastEntries[stream.Labels]
+		if ok && entry.Timestamp.Equal(last.Timestamp) && entry.Line == last.Line {
+			continue
+		}
+
+		if ok && entry.Timestamp.Before(last.Timestamp) {
+			ignored = append(ignored, fmt.Sprintf("entry with timestamp %s ignored, reason: 'entry out of order',\n",
+				entry.Timestamp.String()))
+
+			continue
+		}
+
+		server.lastEntries[stream.Labels] = entry
+		entries = append(entries, entry)
+	}
+
+	if len(ignored) > 0 {
+		errs = append(errs, fmt.Sprintf("%suser '%s', total ignored: %d out of %d for stream: %s",
+			strings.Join(ignored, ""), tenant, len(ignored), len(stream.Entries), stream.Labels))
+	}
+
+	return entries, errs
+}
+
+// validateLabels parses the labels of a stream, checks them against the limits, and returns their normalized form.
+func (limits Limits) validateLabels(input string) (string, error) {
+	scanner := &scanner{input: input}
+
+	selector, err := scanner.selector()
+	if err == nil && !scanner.done() {
+		err = scanner.errorf("unexpected %q after labels", scanner.rest())
+	}
+
+	if err != nil {
+		return "", parseLabelsError(input, err)
+	}
+
+	if len(selector) == 0 {
+		return "", errors.New("error at least one label pair is required per stream")
+	}
+
+	streamLabels := make(map[string]string, len(selector))
+
+	for _, matcher := range selector {
+		if matcher.Type != MatchEqual {
+			return "", parseLabelsError(input, fmt.Errorf("unexpected operator %s for label %q", matcher.Type,
+				matcher.Name))
+		}
+
+		if _, ok := streamLabels[matcher.Name]; ok {
+			return "", fmt.Errorf("stream '%s' has duplicate label name: '%s'", input, matcher.Name)
+		}
+
+		streamLabels[matcher.Name] = matcher.Value
+	}
+
+	normalized := labels.Format(streamLabels)
+
+	if exceeds(len(streamLabels), limits.MaxLabelNamesPerSeries) {
+		return "", fmt.Errorf("entry for series '%s' has %d label names; limit %d", normalized, len(streamLabels),
+			limits.MaxLabelNamesPerSeries)
+	}
+
+	for _, matcher := range selector {
+		if exceeds(len(matcher.Name), limits.MaxLabelNameLength) {
+			return "", fmt.Errorf("stream '%s' has label name too long: '%s'", normalized, matcher.Name)
+		}
+
+		if exceeds(len(matcher.Value), limits.MaxLabelValueLength) {
+			return "", fmt.Errorf("stream '%s' has label value too long: '%s'", normalized, matcher.Value)
+		}
+	}
+
+	return normalized, nil
+}
+
+// parseLabelsError returns the error of Loki for labels that cannot be parsed.
+func parseLabelsError(input string, err error) error {
+	return fmt.Errorf("Error parsing labels '%s' with error: %w", input, err) //nolint:staticcheck // Loki's message.
+}
+
+// exceeds reports whether the size exceeds the limit, treating a limit of zero or less as no limit.
+func exceeds(size, limit int) bool {
+	return limit > 0 && size > limit
+}
@@ -0,0 +1,164 @@
+package lokitest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+)
+
+//nolint:funlen // Most of the function is test cases, no need to worry about length.
+func TestServer_StrictLabels(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		labels         client.LabelString
+		line           string
+		expectedLabels string
+		expectedError  string
+	}{
+		{
+			name:           "valid",
+			labels:         `{level="info", app="test"}`,
+			line:           "test",
+			expectedLabels: `{app="test", level="info"}`,
+		},
+		{
+			name:          "invalid-name",
+			labels:        `{app.name="test"}`,
+			line:          "test",
+			expectedError: `Error parsing labels '{app.name="test"}' with error: `,
+		},
+		{
+			name:          "invalid-operator",
+			labels:        `{app=~"test"}`,
+			line:          "test",
+			expectedError: `Error parsing labels '{app=~"test"}' with error: unexpected operator =~ for label "app"`,
+		},
+		{
+			name:          "no-labels",
+			labels:        `{}`,
+			line:          "test",
+			expectedError: "error at least one label pair is required per stream",
+		},
+		{
+			name:          "duplicate-name",
+			labels:        `{app="a", app="b"}`,
+			line:          "test",
+			expectedError: `stream '{app="a", app="b"}' has duplicate label name: 'app'`,
+		},
+		{
+			name:          "too-many-labels",
+			labels:        `{a="1", b="2", c="3"}`,
+			line:          "test",
+			expectedError: `entry for series '{a="1", b="2", c="3"}' has 3 label names; limit 2`,
+		},
+		{
+			name:          "label-name-too-long",
+			labels:        `{application="test"}`,
+			line:          "test",
+			expectedError: `stream '{application="test"}' has label name too long: 'application'`,
+		},
+		{
+			name:          "label-value-too-long",
+			labels:        `{app="application"}`,
+			line:          "test",
+			expectedError: `stream '{app="application"}' has label value too long: 'application'`,
+		},
+		{
+			name:   "line-too-long",
+			labels: `{app="test"}`,
+			line:   strings.Repeat("a", 17),
+			expectedError: `Max entry size '16' bytes exceeded for stream '{app="test"}' while adding an entry with ` +
+				`length '17' bytes`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := NewServer()
+			httpServer := server.Start()
+
+			defer httpServer.Close()
+
+			server.SetStrict(true)
+			server.SetLimits(Limits{
+				MaxLineSize:            16,
+				MaxLabelNamesPerSeries: 2,
+				MaxLabelNameLength:     8,
+				MaxLabelValueLength:    8,
+			})
+
+			err := client.NewLokiClient(httpServer.URL+PushPath).Push(t.Context(), client.Entry{
+				Timestamp: time.Now(),
+				Labels:    testCase.labels,
+				Line:      testCase.line,
+			})
+
+			if testCase.expectedError == "" {
+				require.NoError(t, err)
+				require.Len(t, server.Streams(), 1)
+				require.Equal(t, testCase.expectedLabels, server.Streams()[0].Labels)
+
+				return
+			}
+
+			var statusErr *client.PushStatusError
+			require.ErrorAs(t, err, &statusErr)
+			require.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+			require.Contains(t, string(statusErr.Body), testCase.expectedError)
+			require.Empty(t, server.Streams())
+		})
+	}
+}
+
+func TestServer_StrictOrdering(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer()
+	httpServer := server.Start()
+
+	defer httpServer.Close()
+
+	server.SetStrict(true)
+
+	lokiClient := client.NewLokiClient(httpServer.URL + PushPath)
+	timestamp := time.Unix(1700000000, 0).UTC()
+	labels := client.LabelMap{"app": "test"}
+
+	require.NoError(t, lokiClient.Push(t.Context(), client.Entry{Timestamp: timestamp, Labels: labels, Line: "first"}))
+
+	// An identical entry is ignored without an error, while the same timestamp with a different line is accepted.
+	require.NoError(t, lokiClient.Push(t.Context(), client.Entry{Timestamp: timestamp, Labels: labels, Line: "first"}))
+	require.NoError(t, lokiClient.Push(t.Context(), client.Entry{Timestamp: timestamp, Labels: labels, Line: "second"}))
+
+	err := lokiClient.Push(t.Context(), client.Entry{Timestamp: timestamp.Add(-time.Second), Labels: labels, Line: "old"})
+
+	var statusErr *client.PushStatusError
+	require.ErrorAs(t, err, &statusErr)
+	require.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+	require.Equal(t, client.ReasonOutOfOrder, statusErr.Reason)
+	require.Equal(t, client.LabelString(`{app="test"}`), statusErr.Stream)
+	require.Equal(t, "entry with timestamp 2023-11-14 22:13:19 +0000 UTC ignored, reason: 'entry out of order',\n"+
+		`user 'fake', total ignored: 1 out of 1 for stream: {app="test"}`, string(statusErr.Body))
+
+	// Other streams are not affected.
+	require.NoError(t, lokiClient.Push(t.Context(), client.Entry{
+		Timestamp: timestamp.Add(-time.Second),
+		Labels:    client.LabelMap{"app": "other"},
+		Line:      "other",
+	}))
+
+	AssertLines(t, server, `{app="test"}`, "first", "second")
+	AssertLines(t, server, `{app="other"}`, "other")
+
+	// Resetting the server disables strict mode and forgets the previous entries.
+	server.Reset()
+	require.NoError(t, lokiClient.Push(t.Context(), client.Entry{Labels: client.LabelString(`{}`), Line: "test"}))
+}
@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/client/sample"
+	"github.com/tslnc04/loki-logger/pkg/internal/labels"
 )
 
 // Handler implements the [slog.Handler] interface and sends logs to a Loki instance. It is best used to create a
@@ -26,11 +28,34 @@ import (
 // documentation. It uses the ReplaceAttr field in a very similar way to the documentation, but the built-in fields
 // attributes are different. Only level and source are supported as time and message are passed directly to loki without
 // the ability to be replaced.
+//
+// # Level
+//
+// The level is added to the stream labels as configured by [Handler.WithLevelLabel], by default as "level" with values
+// such as "info" or "warn" from [client.DefaultLevelMapper]. ReplaceAttr is still called with the level as a
+// [slog.Level] value. If the replacement is a slog.Level as well, it is mapped to the label value, otherwise it is used
+// unchanged.
+//
+// # Sampling
+//
+// A [sample.Sampler] can be set using [Handler.WithSampler]. It is consulted for every enabled record after it has been
+// converted to an entry and any entry it rejects is silently dropped.
+//
+// # Performance
+//
+// The stream labels of the Handler are formatted once when it is created using [Handler.WithAttrs] and cached for each
+// level, so that handling a record does not format the labels again. This does not apply if ReplaceAttr is set, since
+// it may change the level label for each record.
 type Handler struct {
-	client  client.Client
-	options slog.HandlerOptions
-	labels  map[string]string
-	groups  []string
+	client     client.Client
+	options    slog.HandlerOptions
+	labels     map[string]string
+	groups     []string
+	sampler    sample.Sampler
+	levelLabel client.LevelLabel
+	// labelTemplate caches the formatted labels for each level. It must be recreated whenever the labels or the level
+	// label change.
+	labelTemplate *labels.Template
 }
 
 var _ slog.Handler = (*Handler)(nil)
@@ -44,16 +69,20 @@ func NewLogger(client client.Client, options *slog.HandlerOptions) *slog.Logger
 
 // NewHandler creates a new Handler with the given client and options. See the documentation of [Handler] for more
 // information on how the options are used.
-func NewHandler(client client.Client, options *slog.HandlerOptions) *Handler {
+func NewHandler(lokiClient client.Client, options *slog.HandlerOptions) *Handler {
 	if options == nil {
 		options = &slog.HandlerOptions{}
 	}
 
-	return &Handler{
-		client:  client,
-		options: *options,
-		labels:  make(map[string]string),
+	handler := &Handler{
+		client:     lokiClient,
+		options:    *options,
+		labels:     make(map[string]string),
+		levelLabel: client.DefaultLevelLabel(),
 	}
+	handler.preformatLabels()
+
+	return handler
 }
 
 // Enabled returns true if the Handler is enabled for the given level.
@@ -66,13 +95,38 @@ func (handler *Handler) Enabled(_ context.Context, level slog.Level) bool {
 }
 
 // Handle converts the given Record to a format compatible with Loki and pushes it to the Loki instance via the provided
-// client.
+// client. If a sampler is set and it rejects the entry, nothing is pushed and no error is returned. The context is
+// passed on to the client, so labels and metadata added using [client.ContextWithLabels] and
+// [client.ContextWithMetadata] are included in the entry.
 func (handler *Handler) Handle(ctx context.Context, record slog.Record) error {
 	entry := handler.recordToEntry(record)
 
+	if handler.sampler != nil && !handler.sampler.Sample(record.Level, entry) {
+		return nil
+	}
+
 	return handler.client.Push(ctx, entry)
 }
 
+// WithSampler returns a new Handler that uses the given sampler to decide which records are pushed to Loki. A nil
+// sampler disables sampling. The sampler is shared with the original Handler and any Handlers derived from the new one.
+func (handler *Handler) WithSampler(sampler sample.Sampler) *Handler {
+	newHandler := handler.clone()
+	newHandler.sampler = sampler
+
+	return newHandler
+}
+
+// WithLevelLabel returns a new Handler that adds the level to the stream labels as configured by the given LevelLabel.
+// Use a LevelLabel with an empty key to omit the level label.
+func (handler *Handler) WithLevelLabel(levelLabel client.LevelLabel) *Handler {
+	newHandler := handler.clone()
+	newHandler.levelLabel = levelLabel
+	newHandler.preformatLabels()
+
+	return newHandler
+}
+
 // WithAttrs returns a new Handler with the given attributes appended to the existing ones. These appear as stream
 // labels in Loki.
 func (handler *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
@@ -80,6 +134,7 @@ func (handler *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	newState := newHandler.newMutatingHandleState()
 
 	newState.appendAttrs(attrs)
+	newHandler.preformatLabels()
 
 	return newHandler
 }
@@ -100,15 +155,37 @@ func (handler *Handler) WithGroup(name string) slog.Handler {
 // clone returns a copy of the Handler only sharing the client, although the client should be safe to use concurrently.
 func (handler *Handler) clone() *Handler {
 	newHandler := &Handler{
-		client:  handler.client,
-		options: handler.options,
-		labels:  maps.Clone(handler.labels),
-		groups:  slices.Clone(handler.groups),
+		client:        handler.client,
+		options:       handler.options,
+		labels:        maps.Clone(handler.labels),
+		groups:        slices.Clone(handler.groups),
+		sampler:       handler.sampler,
+		levelLabel:    handler.levelLabel,
+		labelTemplate: handler.labelTemplate,
 	}
 
 	return newHandler
 }
 
+// preformatLabels creates the template used to format the labels together with the level label. It modifies the
+// Handler in place, so it must only be called on a Handler that is not shared yet.
+func (handler *Handler) preformatLabels() {
+	handler.labelTemplate = labels.NewTemplate(handler.labels, handler.levelLabel.Key)
+}
+
+// entryLabels returns the stream labels for a record with the given level. Unless ReplaceAttr is set, they are taken
+// from the template without formatting them again.
+func (handler *Handler) entryLabels(level slog.Level) client.LabelString {
+	if handler.options.ReplaceAttr == nil {
+		return client.LabelString(handler.labelTemplate.Format(handler.levelLabel.Name(level)))
+	}
+
+	streamLabels := maps.Clone(handler.labels)
+	handler.newHandleState(streamLabels, nil).appendLevel(level)
+
+	return client.LabelMap(streamLabels).Label()
+}
+
 // recordToEntry converts the given Record to the Entry used by the Loki client. This is what adds the built-in
 // attributes.
 func (handler *Handler) recordToEntry(record slog.Record) client.Entry {
@@ -116,13 +193,8 @@ func (handler *Handler) recordToEntry(record slog.Record) client.Entry {
 		record.Time = time.Now()
 	}
 
-	labels := maps.Clone(handler.labels)
-	state := handler.newHandleState(labels, nil)
-
-	state.appendAttr(slog.Any(slog.LevelKey, record.Level))
-
-	metadata := map[string]string{}
-	state.attrMap = metadata
+	metadata := make(map[string]string, record.NumAttrs())
+	state := handler.newHandleState(metadata, nil)
 
 	if handler.options.AddSource {
 		state.appendAttr(slog.Any(slog.SourceKey, newSource(&record)))
@@ -138,7 +210,7 @@ func (handler *Handler) recordToEntry(record slog.Record) client.Entry {
 
 	return client.Entry{
 		Timestamp:          record.Time,
-		Labels:             client.LabelMap(labels),
+		Labels:             handler.entryLabels(record.Level),
 		Line:               record.Message,
 		StructuredMetadata: metadata,
 	}
@@ -212,6 +284,36 @@ func (state *handleState) appendAttr(attr slog.Attr) {
 	}
 }
 
+// appendLevel adds the level to the state. It calls ReplaceAttr like appendAttr, but maps a [slog.Level] value using
+// the level label of the handler. The key of the level label is used unless ReplaceAttr changed the key.
+func (state *handleState) appendLevel(level slog.Level) {
+	attr := slog.Any(slog.LevelKey, level)
+
+	if state.handler.options.ReplaceAttr != nil {
+		attr = state.handler.options.ReplaceAttr(state.groups, attr)
+		attr.Value = attr.Value.Resolve()
+	}
+
+	replacedLevel, ok := attr.Value.Any().(slog.Level)
+	if !ok {
+		if attr.Value.Kind() == slog.KindGroup {
+			state.appendAttr(attr)
+		} else if !attr.Equal(slog.Attr{}) {
+			state.insertAttr(attr)
+		}
+
+		return
+	}
+
+	if attr.Key == slog.LevelKey {
+		state.handler.levelLabel.Apply(state.attrMap, replacedLevel)
+
+		return
+	}
+
+	state.insertAttr(slog.String(attr.Key, state.handler.levelLabel.Name(replacedLevel)))
+}
+
 // insertAttr appends the given attribute to the state. It assumes the attribute is not a group and has already been
 // resolved. All it does is add the attribute to the map and formats the key.
 func (state *handleState) insertAttr(attr slog.Attr) {
@@ -1,6 +1,7 @@
 package slog
 
 import (
+	"context"
 	"log/slog"
 	"runtime"
 	"testing"
@@ -8,7 +9,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"github.com/tslnc04/loki-logger/pkg/client"
-	"github.com/tslnc04/loki-logger/pkg/internal/fake"
+	"github.com/tslnc04/loki-logger/pkg/client/sample"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
 )
 
 var (
@@ -90,7 +92,7 @@ func TestHandlerLogging(t *testing.T) {
 			level: slog.LevelInfo,
 			expected: client.Entry{
 				Timestamp:          time.Now(),
-				Labels:             client.LabelMap{slog.LevelKey: slog.LevelInfo.String()},
+				Labels:             client.LabelMap{slog.LevelKey: client.LevelNameInfo},
 				Line:               "test",
 				StructuredMetadata: map[string]string{"attrKey": "attrValue"},
 			},
@@ -105,7 +107,7 @@ func TestHandlerLogging(t *testing.T) {
 			level: slog.LevelInfo,
 			expected: client.Entry{
 				Timestamp:          time.Now(),
-				Labels:             client.LabelMap{slog.LevelKey: slog.LevelInfo.String(), "testKey": "testValue"},
+				Labels:             client.LabelMap{slog.LevelKey: client.LevelNameInfo, "testKey": "testValue"},
 				Line:               "test",
 				StructuredMetadata: map[string]string{"attrKey": "attrValue"},
 			},
@@ -119,7 +121,7 @@ func TestHandlerLogging(t *testing.T) {
 			level: slog.LevelInfo,
 			expected: client.Entry{
 				Timestamp:          time.Now(),
-				Labels:             client.LabelMap{slog.LevelKey: slog.LevelInfo.String()},
+				Labels:             client.LabelMap{slog.LevelKey: client.LevelNameInfo},
 				Line:               "test",
 				StructuredMetadata: map[string]string{"testGroup_attrKey": "attrValue"},
 			},
@@ -133,7 +135,7 @@ func TestHandlerLogging(t *testing.T) {
 			level: slog.LevelInfo,
 			expected: client.Entry{
 				Timestamp:          time.Now(),
-				Labels:             client.LabelMap{slog.LevelKey: slog.LevelInfo.String(), "testGroup_testKey": "testValue"},
+				Labels:             client.LabelMap{slog.LevelKey: client.LevelNameInfo, "testGroup_testKey": "testValue"},
 				Line:               "test",
 				StructuredMetadata: map[string]string{"testGroup_attrKey": "attrValue"},
 			},
@@ -148,13 +150,13 @@ func TestHandlerLogging(t *testing.T) {
 			level: slog.LevelInfo,
 			expected: client.Entry{
 				Timestamp: time.Now(),
-				Labels:    client.LabelMap{slog.LevelKey: slog.LevelInfo.String()},
+				Labels:    client.LabelMap{slog.LevelKey: client.LevelNameInfo},
 				Line:      "test",
 				StructuredMetadata: map[string]string{
 					"attrKey":                    "attrValue",
 					slog.SourceKey + "_file":     currentFile,
 					slog.SourceKey + "_function": currentPackage + ".TestHandlerLogging.func7",
-					slog.SourceKey + "_line":     "189",
+					slog.SourceKey + "_line":     "192",
 				},
 			},
 			generateHandler: func(lokiClient client.Client) slog.Handler {
@@ -178,7 +180,8 @@ func TestHandlerLogging(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			t.Parallel()
 
-			fakeServer := fake.NewServer(0)
+			fakeServer := lokitest.NewServer()
+			fakeServer.SetStrict(true)
 			httpServer := fakeServer.Start()
 
 			defer httpServer.Close()
@@ -189,7 +192,6 @@ func TestHandlerLogging(t *testing.T) {
 			logger.LogAttrs(t.Context(), testCase.level, "test", slog.String("attrKey", "attrValue"))
 
 			streams := fakeServer.Streams()
-			defer fakeServer.Close()
 
 			if testCase.name == "not-enabled" {
 				require.Empty(t, streams, "Expected no streams to be sent")
@@ -202,3 +204,190 @@ func TestHandlerLogging(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_AtomicLevel(t *testing.T) {
+	t.Parallel()
+
+	atomicLevel := client.NewAtomicLevel(slog.LevelInfo)
+	handler := NewHandler(nil, &slog.HandlerOptions{Level: atomicLevel})
+
+	require.False(t, handler.Enabled(t.Context(), slog.LevelDebug))
+
+	atomicLevel.SetLevel(slog.LevelDebug)
+	require.True(t, handler.Enabled(t.Context(), slog.LevelDebug))
+
+	withAttrs, ok := handler.WithAttrs([]slog.Attr{slog.String("key", "value")}).(*Handler)
+	require.True(t, ok)
+
+	atomicLevel.SetLevel(slog.LevelError)
+	require.False(t, withAttrs.Enabled(t.Context(), slog.LevelWarn), "Expected derived handlers to share the level")
+}
+
+func TestHandler_WithSampler(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.SetStrict(true)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	handler := NewHandler(lokiClient, nil)
+	sampledHandler := handler.WithSampler(sample.NewCountSampler(time.Minute, 2, 0))
+
+	require.Nil(t, handler.sampler, "Expected the original handler to not be modified")
+
+	logger := slog.New(sampledHandler)
+	for range 5 {
+		logger.InfoContext(t.Context(), "test")
+	}
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 2, "Expected only the first two records to be sent")
+}
+
+//nolint:funlen // This function is long because it tests multiple cases, so not a code quality issue.
+func TestHandler_WithLevelLabel(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		level       slog.Level
+		levelLabel  client.LevelLabel
+		replaceAttr func(groups []string, attr slog.Attr) slog.Attr
+		expected    client.LabelMap
+	}{
+		{
+			name:       "default",
+			level:      slog.LevelWarn + 1,
+			levelLabel: client.DefaultLevelLabel(),
+			expected:   client.LabelMap{client.DefaultLevelKey: client.LevelNameWarn},
+		},
+		{
+			name:  "custom",
+			level: slog.LevelError,
+			levelLabel: client.LevelLabel{
+				Key:    "detected_level",
+				Mapper: client.NewLevelMapper(map[slog.Level]string{slog.LevelError: "ERR"}),
+			},
+			expected: client.LabelMap{"detected_level": "ERR"},
+		},
+		{
+			name:       "omitted",
+			level:      slog.LevelInfo,
+			levelLabel: client.LevelLabel{},
+			expected:   client.LabelMap{},
+		},
+		{
+			name:       "replaced-level",
+			level:      slog.LevelInfo,
+			levelLabel: client.DefaultLevelLabel(),
+			replaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+				if attr.Key == slog.LevelKey {
+					return slog.Any("severity", slog.LevelError)
+				}
+
+				return attr
+			},
+			expected: client.LabelMap{"severity": client.LevelNameError},
+		},
+		{
+			name:       "replaced-string",
+			level:      slog.LevelInfo,
+			levelLabel: client.DefaultLevelLabel(),
+			replaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+				if attr.Key == slog.LevelKey {
+					return slog.String(slog.LevelKey, "INFORMATION")
+				}
+
+				return attr
+			},
+			expected: client.LabelMap{slog.LevelKey: "INFORMATION"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Not strict, since Loki rejects streams without labels, as produced by the omitted case.
+			fakeServer := lokitest.NewServer()
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+			handler := NewHandler(lokiClient, &slog.HandlerOptions{ReplaceAttr: testCase.replaceAttr}).
+				WithLevelLabel(testCase.levelLabel)
+
+			slog.New(handler).Log(t.Context(), testCase.level, "test")
+
+			streams := fakeServer.Streams()
+
+			require.Len(t, streams, 1, "Expected number of streams to match")
+			client.AssertStreamMatchesEntry(t, client.Entry{Labels: testCase.expected, Line: "test"}, streams[0])
+		})
+	}
+}
+
+func TestHandler_HandleContext(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.SetStrict(true)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	ctx := client.ContextWithLabels(t.Context(), "tenant", "a")
+	ctx = client.ContextWithMetadata(ctx, "request_id", "1")
+
+	logger := NewLogger(client.NewLokiClient(httpServer.URL+client.PushPath), nil)
+	logger.InfoContext(ctx, "test", "attrKey", "attrValue")
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 1, "Expected number of streams to match")
+	client.AssertStreamMatchesEntry(t, client.Entry{
+		Labels:             client.LabelMap{slog.LevelKey: client.LevelNameInfo, "tenant": "a"},
+		Line:               "test",
+		StructuredMetadata: map[string]string{"attrKey": "attrValue", "request_id": "1"},
+	}, streams[0])
+}
+
+// discardClient is a client that discards all entries, used to measure the cost of creating them.
+type discardClient struct{}
+
+func (discardClient) Push(context.Context, client.Entry) error {
+	return nil
+}
+
+func BenchmarkHandler_Handle(b *testing.B) {
+	attrs := []slog.Attr{slog.String("app", "bench"), slog.String("env", "test")}
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "test", 0)
+	record.AddAttrs(slog.Int("count", 1))
+
+	benchmarks := []struct {
+		name    string
+		options *slog.HandlerOptions
+	}{
+		{name: "cached", options: nil},
+		{name: "replace-attr", options: &slog.HandlerOptions{ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			return attr
+		}}},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			handler := NewHandler(discardClient{}, benchmark.options).WithAttrs(attrs)
+
+			b.ReportAllocs()
+
+			for b.Loop() {
+				_ = handler.Handle(b.Context(), record)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+// Package zap provides a [zapcore.Core] that sends log entries to a Loki instance.
+//
+// The core can be created directly using [NewCore] and combined with other cores using [zapcore.NewTee], or a
+// [zap.Logger] can be created using [New].
+//
+// # Labels vs Metadata
+//
+// Like the slog and logr adapters, the core treats any fields added to the logger itself using With as labels for the
+// stream in Loki. Fields passed when logging are treated as structured metadata. Namespaces and nested objects are
+// flattened, with their keys joined by an underscore (`_`), the same as groups in the slog adapter.
+package zap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// LevelKey is the key added to the stream labels for a log line unless configured otherwise using
+	// [Core.WithLevelLabel]. Its value is the name of the level from [client.DefaultLevelMapper], e.g. "info". The
+	// zap levels DPanic, Panic, and Fatal are all mapped to [client.LevelCritical].
+	LevelKey = client.DefaultLevelKey
+	// NameKey is the key added to the stream labels for a log line if the logger has a name. Its value is the name as
+	// provided by zap, i.e. the names of the logger joined by ".".
+	NameKey = "name"
+	// SourceKey is the prefix for the keys added to the structured metadata when the caller is available. The actual
+	// keys used are SourceKey+"_function", SourceKey+"_file", and SourceKey+"_line".
+	SourceKey = "source"
+	// StacktraceKey is the key added to the structured metadata when a stack trace is available.
+	StacktraceKey = "stacktrace"
+)
+
+// DefaultFlushTimeout is how long [Core.Sync] waits for the client to flush, unless changed using
+// [Core.WithFlushTimeout]. It keeps Sync from blocking forever if Loki is unreachable.
+const DefaultFlushTimeout = 5 * time.Second
+
+// New creates a new [zap.Logger] with a [Core] using the given client and level enabler. It is equivalent to
+//
+//	zap.New(NewCore(lokiClient, enabler), options...)
+//
+// To add the caller to the structured metadata, pass the [zap.AddCaller] option.
+func New(lokiClient client.Client, enabler zapcore.LevelEnabler, options ...zap.Option) *zap.Logger {
+	return zap.New(NewCore(lokiClient, enabler), options...)
+}
+
+// Core is a [zapcore.Core] that sends log entries to a Loki instance. Fields added using With are used as stream
+// labels and fields passed when logging as structured metadata. It is safe to use concurrently.
+type Core struct {
+	lokiClient client.Client
+	enabler    zapcore.LevelEnabler
+	// labels is a map of labels to add to each log entry. It should never be nil.
+	labels map[string]string
+	// namespace is the prefix of the namespaces opened using With, which applies to all later fields. It is empty or
+	// ends with an underscore.
+	namespace    string
+	levelLabel   client.LevelLabel
+	flushTimeout time.Duration
+}
+
+// Assert that Core implements the [zapcore.Core] interface.
+var _ zapcore.Core = (*Core)(nil)
+
+// NewCore creates a new Core with the given client and level enabler, such as a [zapcore.Level] or a
+// [zap.AtomicLevel].
+func NewCore(lokiClient client.Client, enabler zapcore.LevelEnabler) *Core {
+	return &Core{
+		lokiClient:   lokiClient,
+		enabler:      enabler,
+		labels:       make(map[string]string),
+		levelLabel:   client.DefaultLevelLabel(),
+		flushTimeout: DefaultFlushTimeout,
+	}
+}
+
+// WithLevelLabel returns a new Core that adds the level to the stream labels as configured by the given LevelLabel.
+// Use a LevelLabel with an empty key to omit the level label. The original Core is not modified.
+func (core *Core) WithLevelLabel(levelLabel client.LevelLabel) *Core {
+	newCore := core.clone()
+	newCore.levelLabel = levelLabel
+
+	return newCore
+}
+
+// WithFlushTimeout returns a new Core that waits at most the given duration for the client to flush in [Core.Sync]. It
+// defaults to [DefaultFlushTimeout], and a timeout of zero waits until the flush completes. The original Core is not
+// modified.
+func (core *Core) WithFlushTimeout(timeout time.Duration) *Core {
+	newCore := core.clone()
+	newCore.flushTimeout = timeout
+
+	return newCore
+}
+
+// Enabled reports whether the core is enabled for the given level.
+func (core *Core) Enabled(level zapcore.Level) bool {
+	return core.enabler.Enabled(level)
+}
+
+// Level returns the minimum enabled level of the core. It allows [zapcore.LevelOf] to determine the level without
+// checking every level.
+func (core *Core) Level() zapcore.Level {
+	return zapcore.LevelOf(core.enabler)
+}
+
+// With returns a new Core with the given fields added to the stream labels. A namespace opened by the fields applies
+// to the fields of later calls to With and of each log entry. The original Core is not modified.
+//
+//nolint:ireturn // Necessary to implement the zapcore.Core interface.
+func (core *Core) With(fields []zapcore.Field) zapcore.Core {
+	newCore := core.clone()
+	newCore.namespace = addFields(newCore.labels, core.namespace, fields)
+
+	return newCore
+}
+
+// clone returns a copy of the Core, sharing only the client and level enabler.
+func (core *Core) clone() *Core {
+	return &Core{
+		lokiClient:   core.lokiClient,
+		enabler:      core.enabler,
+		labels:       maps.Clone(core.labels),
+		namespace:    core.namespace,
+		levelLabel:   core.levelLabel,
+		flushTimeout: core.flushTimeout,
+	}
+}
+
+// Check adds the core to the checked entry if it is enabled for the level of the entry.
+func (core *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+
+	return checked
+}
+
+// Write converts the entry and fields to a [client.Entry] and pushes it to Loki. The level and logger name are added to
+// the stream labels, while the fields, caller, and stack trace are added to the structured metadata.
+func (core *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	labels := maps.Clone(core.labels)
+	core.levelLabel.Apply(labels, slogLevel(entry.Level))
+
+	if entry.LoggerName != "" {
+		labels[NameKey] = entry.LoggerName
+	}
+
+	metadata := make(map[string]string)
+	addFields(metadata, core.namespace, fields)
+	addCaller(metadata, entry.Caller)
+
+	if entry.Stack != "" {
+		metadata[StacktraceKey] = entry.Stack
+	}
+
+	return core.lokiClient.Push(context.Background(), client.Entry{
+		Timestamp:          entry.Time,
+		Labels:             client.LabelMap(labels),
+		Line:               entry.Message,
+		StructuredMetadata: metadata,
+	})
+}
+
+// slogLevel converts the zap level to the [slog.Level] scale used by the level label. Levels below debug are mapped to
+// [client.LevelTrace] and levels above error to [client.LevelCritical].
+func slogLevel(level zapcore.Level) slog.Level {
+	switch {
+	case level < zapcore.DebugLevel:
+		return client.LevelTrace
+	case level == zapcore.DebugLevel:
+		return slog.LevelDebug
+	case level == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case level == zapcore.WarnLevel:
+		return slog.LevelWarn
+	case level == zapcore.ErrorLevel:
+		return slog.LevelError
+	default:
+		return client.LevelCritical
+	}
+}
+
+// Sync flushes the client if it implements the [client.Flusher] interface, such as the retry and dedup clients.
+// Otherwise, it does nothing since every entry is pushed as soon as it is written. It waits at most the flush timeout,
+// see [Core.WithFlushTimeout], and returns the error of the context if the flush did not complete in time.
+func (core *Core) Sync() error {
+	flusher, ok := core.lokiClient.(client.Flusher)
+	if !ok {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	if core.flushTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, core.flushTimeout)
+		defer cancel()
+	}
+
+	return flusher.Flush(ctx)
+}
+
+// addFields encodes the fields and adds them to the map with their keys prefixed by the namespace, flattening
+// namespaces and nested objects. It modifies the map in place and returns the namespace that is still open afterwards.
+func addFields(values map[string]string, namespace string, fields []zapcore.Field) string {
+	encoder := zapcore.NewMapObjectEncoder()
+	open := namespace
+
+	for _, field := range fields {
+		field.AddTo(encoder)
+
+		// The encoder nests the following fields in the namespace, but does not expose which namespace is open.
+		if field.Type == zapcore.NamespaceType {
+			open += field.Key + "_"
+		}
+	}
+
+	addEncodedValues(values, namespace, encoder.Fields)
+
+	return open
+}
+
+// addEncodedValues adds the values produced by a [zapcore.MapObjectEncoder] to the map, prefixing their keys. Nested
+// maps are flattened with their keys joined by an underscore.
+func addEncodedValues(values map[string]string, prefix string, encoded map[string]any) {
+	for key, value := range encoded {
+		if nested, ok := value.(map[string]any); ok {
+			addEncodedValues(values, prefix+key+"_", nested)
+
+			continue
+		}
+
+		values[prefix+key] = formatValue(value)
+	}
+}
+
+// formatValue converts a value produced by a [zapcore.MapObjectEncoder] to a string. Arrays and reflected values that
+// are structs, maps, slices, or arrays, including pointers to them, are rendered as JSON, the same as in the logr
+// adapter. Everything else, including values that cannot be encoded as JSON, is rendered using fmt.Sprint.
+func formatValue(value any) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case time.Time:
+		return typed.Format(time.RFC3339Nano)
+	}
+
+	if !isStructured(reflect.ValueOf(value)) {
+		return fmt.Sprint(value)
+	}
+
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(buf)
+}
+
+// isStructured reports whether the value is a struct, map, slice, or array, or a non-nil pointer to one.
+func isStructured(value reflect.Value) bool {
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	switch value.Kind() { //nolint:exhaustive // All other kinds are not structured.
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
+
+// addCaller adds the caller to the metadata, ignoring any values that are empty. It will modify the metadata in place.
+func addCaller(metadata map[string]string, caller zapcore.EntryCaller) {
+	if !caller.Defined {
+		return
+	}
+
+	if caller.Function != "" {
+		metadata[SourceKey+"_function"] = caller.Function
+	}
+
+	if caller.File != "" {
+		metadata[SourceKey+"_file"] = caller.File
+	}
+
+	if caller.Line != 0 {
+		metadata[SourceKey+"_line"] = strconv.Itoa(caller.Line)
+	}
+}
@@ -0,0 +1,257 @@
+package zap
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/client/dedup"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	currentPackage = "github.com/tslnc04/loki-logger/pkg/zap"
+	defaultMessage = "Hello, world!"
+)
+
+var _, currentFile, _, _ = runtime.Caller(0)
+
+func TestNewCore(t *testing.T) {
+	t.Parallel()
+
+	core := NewCore(nil, zapcore.WarnLevel)
+	require.NotNil(t, core)
+	require.Empty(t, core.labels)
+	require.Equal(t, zapcore.WarnLevel, core.Level())
+	require.True(t, core.Enabled(zapcore.ErrorLevel))
+	require.False(t, core.Enabled(zapcore.InfoLevel))
+}
+
+func TestCore_With(t *testing.T) {
+	t.Parallel()
+
+	core := NewCore(nil, zapcore.InfoLevel)
+
+	withCore, ok := core.With([]zapcore.Field{zap.String("key", "value")}).(*Core)
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"key": "value"}, withCore.labels)
+
+	// Ensure the original core is not modified.
+	require.Empty(t, core.labels)
+}
+
+//nolint:funlen // This function is long because it tests multiple cases, so not a code quality issue.
+func TestCoreLogging(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		log      func(logger *zap.Logger)
+		expected []client.Entry
+	}{
+		{
+			name: "basic",
+			log: func(logger *zap.Logger) {
+				logger.Info(defaultMessage, zap.Int("count", 1), zap.Bool("ok", true))
+			},
+			expected: []client.Entry{{
+				Labels:             client.LabelMap{LevelKey: "info"},
+				Line:               defaultMessage,
+				StructuredMetadata: map[string]string{"count": "1", "ok": "true"},
+			}},
+		},
+		{
+			name: "with-fields-and-name",
+			log: func(logger *zap.Logger) {
+				logger.Named("parent").Named("child").With(zap.String("app", "test")).
+					Warn(defaultMessage, zap.Error(errors.New("failed")))
+			},
+			expected: []client.Entry{{
+				Labels:             client.LabelMap{LevelKey: "warn", NameKey: "parent.child", "app": "test"},
+				Line:               defaultMessage,
+				StructuredMetadata: map[string]string{"error": "failed"},
+			}},
+		},
+		{
+			name: "nested",
+			log: func(logger *zap.Logger) {
+				logger.Info(defaultMessage, zap.Strings("list", []string{"a", "b"}), zap.Namespace("ns"),
+					zap.Time("time", time.Date(2025, 5, 27, 0, 0, 0, 0, time.UTC)))
+			},
+			expected: []client.Entry{{
+				Labels: client.LabelMap{LevelKey: "info"},
+				Line:   defaultMessage,
+				StructuredMetadata: map[string]string{
+					"list":    `["a","b"]`,
+					"ns_time": "2025-05-27T00:00:00Z",
+				},
+			}},
+		},
+		{
+			name: "namespace-with",
+			log: func(logger *zap.Logger) {
+				logger.With(zap.Namespace("req")).With(zap.String("id", "1")).
+					Info(defaultMessage, zap.String("a", "b"), zap.Namespace("inner"), zap.Int("c", 2))
+			},
+			expected: []client.Entry{{
+				Labels:             client.LabelMap{LevelKey: "info", "req_id": "1"},
+				Line:               defaultMessage,
+				StructuredMetadata: map[string]string{"req_a": "b", "req_inner_c": "2"},
+			}},
+		},
+		{
+			name: "reflected",
+			log: func(logger *zap.Logger) {
+				logger.Info(defaultMessage, zap.Any("struct", struct{ X int }{X: 1}),
+					zap.Reflect("map", map[string]int{"a": 1}), zap.Any("duration", time.Second))
+			},
+			expected: []client.Entry{{
+				Labels:             client.LabelMap{LevelKey: "info"},
+				Line:               defaultMessage,
+				StructuredMetadata: map[string]string{"struct": `{"X":1}`, "map": `{"a":1}`, "duration": "1s"},
+			}},
+		},
+		{
+			name: "not-enabled",
+			log: func(logger *zap.Logger) {
+				logger.Debug(defaultMessage)
+			},
+			expected: []client.Entry{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+			testCase.log(New(lokiClient, zapcore.InfoLevel))
+
+			streams := fakeServer.Streams()
+
+			require.Len(t, streams, len(testCase.expected), "Expected number of streams to match")
+
+			for i, expected := range testCase.expected {
+				client.AssertStreamMatchesEntry(t, expected, streams[i])
+			}
+		})
+	}
+}
+
+func TestCoreLogging_Caller(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	logger := New(lokiClient, zapcore.InfoLevel, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
+
+	logger.Info(defaultMessage)
+	logger.Error(defaultMessage)
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 2, "Expected number of streams to match")
+	client.AssertStreamMatchesEntry(t, client.Entry{
+		Labels: client.LabelMap{LevelKey: "info"},
+		Line:   defaultMessage,
+		StructuredMetadata: map[string]string{
+			SourceKey + "_function": currentPackage + ".TestCoreLogging_Caller",
+			SourceKey + "_file":     currentFile,
+			SourceKey + "_line":     "163",
+		},
+	}, streams[0])
+
+	metadata := map[string]string{}
+	for _, label := range streams[1].Entries[0].StructuredMetadata {
+		metadata[label.Name] = label.Value
+	}
+
+	require.Contains(t, metadata[StacktraceKey], currentPackage+".TestCoreLogging_Caller")
+}
+
+func TestCore_Sync(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	logger := New(dedup.NewDedupClient(lokiClient).WithWindow(time.Hour), zapcore.InfoLevel)
+
+	logger.Info(defaultMessage)
+	require.NoError(t, logger.Sync())
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 1, "Expected the pending entry to be flushed")
+	require.NoError(t, New(lokiClient, zapcore.InfoLevel).Sync())
+}
+
+// blockingFlusher is a client whose Flush blocks until the context is done, like a client that cannot reach Loki.
+type blockingFlusher struct{}
+
+func (blockingFlusher) Push(context.Context, client.Entry) error {
+	return nil
+}
+
+func (blockingFlusher) Flush(ctx context.Context) error {
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func TestCore_SyncTimeout(t *testing.T) {
+	t.Parallel()
+
+	core := NewCore(blockingFlusher{}, zapcore.InfoLevel)
+	require.Equal(t, DefaultFlushTimeout, core.flushTimeout)
+
+	withTimeout := core.WithFlushTimeout(10 * time.Millisecond)
+	require.ErrorIs(t, zap.New(withTimeout).Sync(), context.DeadlineExceeded)
+
+	// The original Core is not modified.
+	require.Equal(t, DefaultFlushTimeout, core.flushTimeout)
+}
+
+func TestCore_WithLevelLabel(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+	core := NewCore(lokiClient, zapcore.DebugLevel)
+	customCore := core.WithLevelLabel(client.LevelLabel{Key: "detected_level"})
+
+	require.Equal(t, client.DefaultLevelLabel(), core.levelLabel, "Expected the original core to not be modified")
+
+	zap.New(core).Debug(defaultMessage)
+	zap.New(core).DPanic(defaultMessage)
+	zap.New(customCore).Error(defaultMessage)
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 3, "Expected number of streams to match")
+	require.Equal(t, `{level="debug"}`, streams[0].Labels)
+	require.Equal(t, `{level="critical"}`, streams[1].Labels)
+	require.Equal(t, `{detected_level="error"}`, streams[2].Labels)
+}
@@ -0,0 +1,143 @@
+package zerolog
+
+// scanObject calls field for each top-level field of the JSON object in data, passing the unquoted key and the raw
+// value. It only finds the boundaries of the values without decoding them, which avoids the cost of unmarshaling the
+// whole event. It is not a validating parser and accepts some invalid JSON. Anything after the closing brace, such as
+// the trailing newline written by zerolog, is ignored.
+func scanObject(data []byte, field func(key string, value []byte)) error {
+	pos := skipSpace(data, 0)
+	if pos >= len(data) || data[pos] != '{' {
+		return errMalformed
+	}
+
+	pos = skipSpace(data, pos+1)
+	if pos < len(data) && data[pos] == '}' {
+		return nil
+	}
+
+	for {
+		keyEnd, err := scanString(data, pos)
+		if err != nil {
+			return err
+		}
+
+		key := stringValue(data[pos:keyEnd])
+
+		pos = skipSpace(data, keyEnd)
+		if pos >= len(data) || data[pos] != ':' {
+			return errMalformed
+		}
+
+		pos = skipSpace(data, pos+1)
+
+		valueEnd, err := scanValue(data, pos)
+		if err != nil {
+			return err
+		}
+
+		field(key, data[pos:valueEnd])
+
+		pos = skipSpace(data, valueEnd)
+		if pos >= len(data) {
+			return errMalformed
+		}
+
+		switch data[pos] {
+		case ',':
+			pos = skipSpace(data, pos+1)
+		case '}':
+			return nil
+		default:
+			return errMalformed
+		}
+	}
+}
+
+// scanValue returns the position just after the JSON value starting at pos.
+func scanValue(data []byte, pos int) (int, error) {
+	if pos >= len(data) {
+		return 0, errMalformed
+	}
+
+	switch data[pos] {
+	case '"':
+		return scanString(data, pos)
+	case '{', '[':
+		return scanNested(data, pos)
+	}
+
+	end := pos
+	for end < len(data) && !isDelimiter(data[end]) {
+		end++
+	}
+
+	if end == pos {
+		return 0, errMalformed
+	}
+
+	return end, nil
+}
+
+// scanString returns the position just after the JSON string starting at pos.
+func scanString(data []byte, pos int) (int, error) {
+	if pos >= len(data) || data[pos] != '"' {
+		return 0, errMalformed
+	}
+
+	for end := pos + 1; end < len(data); end++ {
+		switch data[end] {
+		case '\\':
+			end++
+		case '"':
+			return end + 1, nil
+		}
+	}
+
+	return 0, errMalformed
+}
+
+// scanNested returns the position just after the JSON object or array starting at pos.
+func scanNested(data []byte, pos int) (int, error) {
+	depth := 0
+
+	for end := pos; end < len(data); end++ {
+		switch data[end] {
+		case '"':
+			stringEnd, err := scanString(data, end)
+			if err != nil {
+				return 0, err
+			}
+
+			end = stringEnd - 1
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+
+			if depth == 0 {
+				return end + 1, nil
+			}
+		}
+	}
+
+	return 0, errMalformed
+}
+
+// skipSpace returns the position of the first non-whitespace character at or after pos.
+func skipSpace(data []byte, pos int) int {
+	for pos < len(data) && isSpace(data[pos]) {
+		pos++
+	}
+
+	return pos
+}
+
+// isSpace reports whether the character is JSON whitespace.
+func isSpace(char byte) bool {
+	return char == ' ' || char == '\t' || char == '\n' || char == '\r'
+}
+
+// isDelimiter reports whether the character ends a JSON number or literal.
+func isDelimiter(char byte) bool {
+	return char == ',' || char == '}' || char == ']' || isSpace(char)
+}
@@ -0,0 +1,279 @@
+// Package zerolog provides a [zerolog.LevelWriter] that sends log entries to a Loki instance.
+//
+// zerolog serializes every event to JSON before writing it. Instead of pushing that JSON as the line, the [LokiWriter]
+// parses the event: the message becomes the line, the level becomes a stream label, the timestamp becomes the
+// timestamp of the entry, and all other fields become structured metadata. Fields can be promoted to stream labels
+// using [LokiWriter.WithLabelFields].
+//
+// The field names are taken from the zerolog globals, such as [zerolog.MessageFieldName], at the time of each write.
+//
+//	writer := lokizerolog.NewLokiWriter(lokiClient, map[string]string{"app": "example"})
+//	logger := zerolog.New(writer).With().Timestamp().Logger()
+package zerolog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/tslnc04/loki-logger/pkg/client"
+)
+
+// LevelKey is the key added to the stream labels for a log line unless configured otherwise using
+// [LokiWriter.WithLevelLabel]. Its value is the name of the level from [client.DefaultLevelMapper], e.g. "info". The
+// zerolog levels fatal and panic are both mapped to [client.LevelCritical]. Levels that zerolog cannot parse are used
+// unchanged. It is not added for events without a level.
+const LevelKey = client.DefaultLevelKey
+
+// errMalformed is returned when an event is not a JSON object.
+var errMalformed = errors.New("malformed zerolog event")
+
+// LokiWriter is a writer that sends zerolog events to a Loki instance. It implements the [zerolog.LevelWriter]
+// interface. Writes are assumed to always be a single JSON event, as written by a [zerolog.Logger]. If an event cannot
+// be parsed, it is pushed as the line without any structured metadata.
+type LokiWriter struct {
+	lokiClient client.Client
+	labels     map[string]string
+	// labelFields is the set of fields that are added to the stream labels instead of the structured metadata.
+	labelFields map[string]struct{}
+	levelLabel  client.LevelLabel
+}
+
+// Assert that LokiWriter implements the [zerolog.LevelWriter] interface.
+var _ zerolog.LevelWriter = (*LokiWriter)(nil)
+
+// NewLokiWriter creates a new LokiWriter with the given client and labels. Labels may be nil.
+func NewLokiWriter(lokiClient client.Client, labels map[string]string) *LokiWriter {
+	return &LokiWriter{
+		lokiClient:  lokiClient,
+		labels:      maps.Clone(labels),
+		labelFields: make(map[string]struct{}),
+		levelLabel:  client.DefaultLevelLabel(),
+	}
+}
+
+// WithLabels returns a new LokiWriter with the labels added. Keys that already exist will be overwritten. It is safe to
+// call concurrently from multiple goroutines.
+func (writer *LokiWriter) WithLabels(labels map[string]string) *LokiWriter {
+	newWriter := writer.Clone()
+
+	if newWriter.labels == nil {
+		newWriter.labels = make(map[string]string, len(labels))
+	}
+
+	maps.Copy(newWriter.labels, labels)
+
+	return newWriter
+}
+
+// WithLabelFields returns a new LokiWriter that adds the given fields of each event to the stream labels instead of the
+// structured metadata. Fields with values that are not strings are added as their JSON representation. It is safe to
+// call concurrently from multiple goroutines.
+func (writer *LokiWriter) WithLabelFields(fields ...string) *LokiWriter {
+	newWriter := writer.Clone()
+
+	for _, field := range fields {
+		newWriter.labelFields[field] = struct{}{}
+	}
+
+	return newWriter
+}
+
+// WithLevelLabel returns a new LokiWriter that adds the level to the stream labels as configured by the given
+// LevelLabel. Use a LevelLabel with an empty key to omit the level label. It is safe to call concurrently from multiple
+// goroutines.
+func (writer *LokiWriter) WithLevelLabel(levelLabel client.LevelLabel) *LokiWriter {
+	newWriter := writer.Clone()
+	newWriter.levelLabel = levelLabel
+
+	return newWriter
+}
+
+// Clone returns a copy of the LokiWriter, sharing only the Loki client. It is safe to call concurrently from multiple
+// goroutines.
+func (writer *LokiWriter) Clone() *LokiWriter {
+	return &LokiWriter{
+		lokiClient:  writer.lokiClient,
+		labels:      maps.Clone(writer.labels),
+		labelFields: maps.Clone(writer.labelFields),
+		levelLabel:  writer.levelLabel,
+	}
+}
+
+// Write pushes the event to Loki, taking the level from the level field of the event. It is safe to call concurrently
+// from multiple goroutines.
+func (writer *LokiWriter) Write(event []byte) (int, error) {
+	return writer.WriteLevel(zerolog.NoLevel, event)
+}
+
+// WriteLevel pushes the event to Loki with the given level. If the level is [zerolog.NoLevel], the level field of the
+// event is used instead, if any. It does not modify the event and returns its original length. It is safe to call
+// concurrently from multiple goroutines.
+func (writer *LokiWriter) WriteLevel(level zerolog.Level, event []byte) (int, error) {
+	entry := writer.eventToEntry(level, event)
+
+	err := writer.lokiClient.Push(context.Background(), entry)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(event), nil
+}
+
+// eventToEntry converts the JSON event to an entry. If the event cannot be parsed, the whole event is used as the line.
+func (writer *LokiWriter) eventToEntry(level zerolog.Level, event []byte) client.Entry {
+	labels := make(map[string]string, len(writer.labels)+1)
+	maps.Copy(labels, writer.labels)
+
+	entry := client.Entry{
+		Labels:             client.LabelMap(labels),
+		StructuredMetadata: make(map[string]string),
+	}
+
+	err := scanObject(event, func(key string, value []byte) {
+		writer.addField(&entry, key, value)
+	})
+	if err != nil {
+		clear(labels)
+		maps.Copy(labels, writer.labels)
+
+		entry.Timestamp = time.Time{}
+		entry.Line = string(trimNewlines(event))
+		entry.StructuredMetadata = nil
+	}
+
+	if level != zerolog.NoLevel {
+		writer.addLevel(labels, level.String())
+	}
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	return entry
+}
+
+// addField adds a single field of the event to the entry, depending on its key.
+func (writer *LokiWriter) addField(entry *client.Entry, key string, value []byte) {
+	labels, _ := entry.Labels.(client.LabelMap)
+
+	switch key {
+	case zerolog.MessageFieldName:
+		entry.Line = stringValue(value)
+	case zerolog.LevelFieldName:
+		writer.addLevel(labels, stringValue(value))
+	case zerolog.TimestampFieldName:
+		timestamp, err := parseTimestamp(value, zerolog.TimeFieldFormat)
+		if err != nil {
+			entry.StructuredMetadata[key] = stringValue(value)
+
+			return
+		}
+
+		entry.Timestamp = timestamp
+	default:
+		if _, ok := writer.labelFields[key]; ok {
+			labels[key] = stringValue(value)
+		} else {
+			entry.StructuredMetadata[key] = stringValue(value)
+		}
+	}
+}
+
+// addLevel adds the level label for the level with the given name to the labels. Names that zerolog cannot parse are
+// used as the value of the label unchanged.
+func (writer *LokiWriter) addLevel(labels map[string]string, name string) {
+	if writer.levelLabel.Key == "" {
+		return
+	}
+
+	level, err := zerolog.ParseLevel(name)
+	if err != nil || level == zerolog.NoLevel {
+		labels[writer.levelLabel.Key] = name
+
+		return
+	}
+
+	writer.levelLabel.Apply(labels, slogLevel(level))
+}
+
+// slogLevel converts the zerolog level to the [slog.Level] scale used by the level label. Levels below trace are mapped
+// to [client.LevelTrace] as well and levels above error to [client.LevelCritical].
+func slogLevel(level zerolog.Level) slog.Level {
+	switch {
+	case level <= zerolog.TraceLevel:
+		return client.LevelTrace
+	case level == zerolog.DebugLevel:
+		return slog.LevelDebug
+	case level == zerolog.InfoLevel:
+		return slog.LevelInfo
+	case level == zerolog.WarnLevel:
+		return slog.LevelWarn
+	case level == zerolog.ErrorLevel:
+		return slog.LevelError
+	default:
+		return client.LevelCritical
+	}
+}
+
+// parseTimestamp parses the value of the timestamp field using the given [zerolog.TimeFieldFormat].
+func parseTimestamp(value []byte, format string) (time.Time, error) {
+	switch format {
+	case zerolog.TimeFormatUnix, zerolog.TimeFormatUnixMs, zerolog.TimeFormatUnixMicro, zerolog.TimeFormatUnixNano:
+		number, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		switch format {
+		case zerolog.TimeFormatUnixMs:
+			return time.UnixMilli(number), nil
+		case zerolog.TimeFormatUnixMicro:
+			return time.UnixMicro(number), nil
+		case zerolog.TimeFormatUnixNano:
+			return time.Unix(0, number), nil
+		default:
+			return time.Unix(number, 0), nil
+		}
+	default:
+		return time.Parse(format, stringValue(value))
+	}
+}
+
+// stringValue returns the value as a string. JSON strings are unquoted, while all other values are returned as their
+// JSON representation.
+func stringValue(value []byte) string {
+	if len(value) < 2 || value[0] != '"' {
+		return string(value)
+	}
+
+	unquoted := value[1 : len(value)-1]
+	for _, char := range unquoted {
+		if char == '\\' {
+			var decoded string
+
+			err := json.Unmarshal(value, &decoded)
+			if err != nil {
+				return string(unquoted)
+			}
+
+			return decoded
+		}
+	}
+
+	return string(unquoted)
+}
+
+// trimNewlines returns the event without any trailing newline characters, the same as the log adapter.
+func trimNewlines(event []byte) []byte {
+	for len(event) > 0 && (event[len(event)-1] == '\n' || event[len(event)-1] == '\r') {
+		event = event[:len(event)-1]
+	}
+
+	return event
+}
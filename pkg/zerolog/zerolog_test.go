@@ -0,0 +1,331 @@
+package zerolog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/tslnc04/loki-logger/pkg/client"
+	"github.com/tslnc04/loki-logger/pkg/lokitest"
+)
+
+const defaultMessage = "Hello, world!"
+
+var testTimestamp = time.Date(2025, 5, 27, 0, 0, 0, 0, time.UTC)
+
+func TestLokiWriter_With(t *testing.T) {
+	t.Parallel()
+
+	writer := NewLokiWriter(nil, nil)
+	require.Empty(t, writer.labels)
+
+	withLabels := writer.WithLabels(map[string]string{"app": "test"}).WithLabelFields("component")
+	require.Equal(t, map[string]string{"app": "test"}, withLabels.labels)
+	require.Contains(t, withLabels.labelFields, "component")
+
+	// Ensure the original writer is not modified.
+	require.Empty(t, writer.labels)
+	require.Empty(t, writer.labelFields)
+}
+
+//nolint:funlen // This function is long because it tests multiple cases, so not a code quality issue.
+func TestLokiWriterLogging(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		log      func(logger zerolog.Logger)
+		expected client.Entry
+	}{
+		{
+			name: "basic",
+			log: func(logger zerolog.Logger) {
+				logger.Info().Str("key", "value").Int("count", 1).Msg(defaultMessage)
+			},
+			expected: client.Entry{
+				Labels:             client.LabelMap{"app": "test", LevelKey: "info"},
+				Line:               defaultMessage,
+				StructuredMetadata: map[string]string{"key": "value", "count": "1"},
+			},
+		},
+		{
+			name: "label-fields",
+			log: func(logger zerolog.Logger) {
+				componentLogger := logger.With().Str("component", "db").Logger()
+				componentLogger.Warn().Msg(defaultMessage)
+			},
+			expected: client.Entry{
+				Labels:             client.LabelMap{"app": "test", "component": "db", LevelKey: "warn"},
+				Line:               defaultMessage,
+				StructuredMetadata: map[string]string{},
+			},
+		},
+		{
+			name: "nested-and-escaped",
+			log: func(logger zerolog.Logger) {
+				logger.Error().
+					Dict("dict", zerolog.Dict().Str("a", "b}")).
+					Strs("list", []string{"x", "y"}).
+					Bool("ok", false).
+					Msg("quote \" and\nnewline")
+			},
+			expected: client.Entry{
+				Labels: client.LabelMap{"app": "test", LevelKey: "error"},
+				Line:   "quote \" and\nnewline",
+				StructuredMetadata: map[string]string{
+					"dict": `{"a":"b}"}`,
+					"list": `["x","y"]`,
+					"ok":   "false",
+				},
+			},
+		},
+		{
+			name: "no-level",
+			log: func(logger zerolog.Logger) {
+				logger.Log().Msg(defaultMessage)
+			},
+			expected: client.Entry{
+				Labels:             client.LabelMap{"app": "test"},
+				Line:               defaultMessage,
+				StructuredMetadata: map[string]string{},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			lokiClient := client.NewLokiClient(httpServer.URL + client.PushPath)
+			writer := NewLokiWriter(lokiClient, map[string]string{"app": "test"}).WithLabelFields("component")
+			testCase.log(zerolog.New(writer))
+
+			streams := fakeServer.Streams()
+
+			require.Len(t, streams, 1, "Expected number of streams to match")
+
+			expected := testCase.expected
+			if len(expected.StructuredMetadata) == 0 {
+				expected.StructuredMetadata = nil
+			}
+
+			client.AssertStreamMatchesEntry(t, expected, streams[0])
+		})
+	}
+}
+
+func TestLokiWriter_Write(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		event    string
+		expected client.Entry
+	}{
+		{
+			name:  "level-field",
+			event: `{"level":"debug","time":"2025-05-27T00:00:00Z","message":"test"}` + "\n",
+			expected: client.Entry{
+				Timestamp: testTimestamp,
+				Labels:    client.LabelMap{LevelKey: "debug"},
+				Line:      "test",
+			},
+		},
+		{
+			name:  "invalid-time",
+			event: `{"time":"yesterday","message":"test"}`,
+			expected: client.Entry{
+				Labels:             client.LabelMap{},
+				Line:               "test",
+				StructuredMetadata: map[string]string{"time": "yesterday"},
+			},
+		},
+		{
+			name:  "malformed",
+			event: `{"level":"info","message":` + "\n",
+			expected: client.Entry{
+				Labels: client.LabelMap{},
+				Line:   `{"level":"info","message":`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeServer := lokitest.NewServer()
+			httpServer := fakeServer.Start()
+
+			defer httpServer.Close()
+
+			writer := NewLokiWriter(client.NewLokiClient(httpServer.URL+client.PushPath), nil)
+
+			written, err := writer.Write([]byte(testCase.event))
+			require.NoError(t, err)
+			require.Len(t, testCase.event, written)
+
+			streams := fakeServer.Streams()
+
+			require.Len(t, streams, 1, "Expected number of streams to match")
+			client.AssertStreamMatchesEntry(t, testCase.expected, streams[0])
+		})
+	}
+}
+
+func TestLokiWriter_WriteError(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	fakeServer.FailNext(1)
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	writer := NewLokiWriter(client.NewLokiClient(httpServer.URL+client.PushPath), nil)
+
+	written, err := writer.Write([]byte(`{"message":"test"}`))
+	require.Error(t, err)
+	require.Zero(t, written)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		value    string
+		format   string
+		expected time.Time
+	}{
+		{
+			name:     "unix",
+			value:    "1748304000",
+			format:   zerolog.TimeFormatUnix,
+			expected: testTimestamp,
+		},
+		{
+			name:     "unix-ms",
+			value:    "1748304000000",
+			format:   zerolog.TimeFormatUnixMs,
+			expected: testTimestamp,
+		},
+		{
+			name:     "unix-micro",
+			value:    "1748304000000000",
+			format:   zerolog.TimeFormatUnixMicro,
+			expected: testTimestamp,
+		},
+		{
+			name:     "unix-nano",
+			value:    "1748304000000000000",
+			format:   zerolog.TimeFormatUnixNano,
+			expected: testTimestamp,
+		},
+		{
+			name:     "layout",
+			value:    `"2025-05-27"`,
+			format:   time.DateOnly,
+			expected: testTimestamp,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			timestamp, err := parseTimestamp([]byte(testCase.value), testCase.format)
+			require.NoError(t, err)
+			require.True(t, testCase.expected.Equal(timestamp))
+		})
+	}
+
+	_, err := parseTimestamp([]byte(`"now"`), zerolog.TimeFormatUnix)
+	require.Error(t, err)
+}
+
+func TestScanObject(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		data     string
+		expected map[string]string
+		err      bool
+	}{
+		{
+			name:     "empty",
+			data:     ` { } `,
+			expected: map[string]string{},
+		},
+		{
+			name:     "whitespace",
+			data:     `{ "a" : 1 , "b\"c" : [ 1, "]" ] , "d": null }`,
+			expected: map[string]string{"a": "1", `b"c`: `[ 1, "]" ]`, "d": "null"},
+		},
+		{name: "not-object", data: `[]`, err: true},
+		{name: "missing-colon", data: `{"a" 1}`, err: true},
+		{name: "missing-value", data: `{"a":}`, err: true},
+		{name: "unterminated-string", data: `{"a":"b}`, err: true},
+		{name: "unterminated-object", data: `{"a":{"b":1}`, err: true},
+		{name: "unterminated-nested-string", data: `{"a":{"b":"c}}`, err: true},
+		{name: "missing-comma", data: `{"a":1 "b":2}`, err: true},
+		{name: "unquoted-key", data: `{a:1}`, err: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			fields := map[string]string{}
+			err := scanObject([]byte(testCase.data), func(key string, value []byte) {
+				fields[key] = string(value)
+			})
+
+			if testCase.err {
+				require.ErrorIs(t, err, errMalformed)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, fields)
+		})
+	}
+}
+
+func TestLokiWriter_WithLevelLabel(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := lokitest.NewServer()
+	httpServer := fakeServer.Start()
+
+	defer httpServer.Close()
+
+	writer := NewLokiWriter(client.NewLokiClient(httpServer.URL+client.PushPath), nil)
+	customWriter := writer.WithLevelLabel(client.LevelLabel{Key: "detected_level"})
+
+	require.Equal(t, client.DefaultLevelLabel(), writer.levelLabel, "Expected the original writer to not be modified")
+
+	for _, event := range []string{`{"level":"trace"}`, `{"level":"fatal"}`, `{"level":"notice"}`} {
+		_, err := writer.Write([]byte(event))
+		require.NoError(t, err)
+	}
+
+	_, err := customWriter.WriteLevel(zerolog.WarnLevel, []byte(`{}`))
+	require.NoError(t, err)
+
+	streams := fakeServer.Streams()
+
+	require.Len(t, streams, 4, "Expected number of streams to match")
+	require.Equal(t, `{level="trace"}`, streams[0].Labels)
+	require.Equal(t, `{level="critical"}`, streams[1].Labels)
+	require.Equal(t, `{level="notice"}`, streams[2].Labels)
+	require.Equal(t, `{detected_level="warn"}`, streams[3].Labels)
+}